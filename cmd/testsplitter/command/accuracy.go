@@ -0,0 +1,96 @@
+package command
+
+import (
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/takuo/go-testsplitter/internal/history"
+	"github.com/takuo/go-testsplitter/internal/plan"
+)
+
+// nodeResultFile matches the go test -json files written by the node scripts, test-N-M.jsonl
+var nodeResultFile = regexp.MustCompile(`^test-(\d+)-\d+\.jsonl(\.gz)?$`)
+
+// AccuracyCmd compares the saved assignment with the actual results of the nodes
+type AccuracyCmd struct {
+	ScriptsDir string `short:"o" long:"scripts-dir" default:"./test-scripts" help:"Directory containing the scripts and the assignment (plan.json) generated by the split command"`
+	JSONDir    string `short:"j" long:"json-dir" default:"./test-json" help:"Directory containing the result files of the nodes (test-N-M.jsonl)"`
+	Top        int    `long:"top" default:"10" help:"Number of the biggest mispredictions to list"`
+}
+
+// Run prints the predicted and actual durations per node and the biggest mispredictions
+func (c *AccuracyCmd) Run() error {
+	p, err := plan.Load(filepath.Join(c.ScriptsDir, plan.FileName))
+	if err != nil {
+		return fmt.Errorf("failed to load plan: %w", err)
+	}
+	results, files, err := loadNodeResults(c.JSONDir)
+	if err != nil {
+		return err
+	}
+	log.Printf("Loaded results of %d nodes from %d files in %s\n", len(results), files, c.JSONDir)
+	acc := plan.Compare(p, results)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NODE\tPREDICTED\tACTUAL\tERROR\tSUM\tWALL\tTESTS\tNO RESULT")
+	var predicted, actual, wall []time.Duration
+	for _, n := range acc.Nodes {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%+.1f%%\t%s\t%s\t%d\t%d\n", n.Index,
+			n.Predicted.Round(time.Millisecond), n.Actual.Round(time.Millisecond), n.RelError()*100,
+			n.Sum.Round(time.Millisecond), n.Wall.Round(time.Millisecond), n.Tests, n.Missing)
+		predicted = append(predicted, n.Predicted)
+		actual = append(actual, n.Actual)
+		wall = append(wall, n.Wall)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Printf("\nImbalance (longest / mean): predicted %.2f, actual %.2f, wall %.2f\n",
+		plan.Imbalance(predicted), plan.Imbalance(actual), plan.Imbalance(wall))
+
+	if c.Top <= 0 || len(acc.Tests) == 0 {
+		return nil
+	}
+	fmt.Printf("\nBiggest mispredictions:\n")
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TEST\tPACKAGE\tNODE\tPREDICTED\tACTUAL\tERROR")
+	for _, t := range acc.Tests[:min(c.Top, len(acc.Tests))] {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t%+.1f%%\n", t.Function, t.Package, t.Node,
+			t.Predicted.Round(time.Millisecond), t.Actual.Round(time.Millisecond), t.RelError()*100)
+	}
+	return w.Flush()
+}
+
+// loadNodeResults reads the result files of every node in dir, keyed by node index
+func loadNodeResults(dir string) (results map[int]history.History, files int, err error) {
+	results = make(map[int]history.History)
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		m := nodeResultFile.FindStringSubmatch(d.Name())
+		if m == nil || d.IsDir() {
+			return nil
+		}
+		node, _ := strconv.Atoi(m[1])
+		data, err := parseHistoryFile(path)
+		if err != nil {
+			log.Printf("Failed to read %s: %v\n", path, err)
+			return nil
+		}
+		if results[node] == nil {
+			results[node] = make(history.History)
+		}
+		results[node].Merge(data)
+		files++
+		return nil
+	})
+	return results, files, err
+}
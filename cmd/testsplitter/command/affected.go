@@ -0,0 +1,52 @@
+package command
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/takuo/go-testsplitter/internal/affected"
+	"github.com/takuo/go-testsplitter/internal/scanner"
+)
+
+// AffectedFlags narrow the packages to the ones affected by a change
+type AffectedFlags struct {
+	Since string `long:"since" placeholder:"REF" help:"Test only the packages affected by the files changed since the git revision (since its merge base with HEAD), through the reverse import graph"`
+}
+
+// affectedPackages keeps only the packages affected by the files changed since
+// --since and logs why each of them is selected. If the import graph cannot be
+// listed, every package is kept.
+func (c *CLI) affectedPackages(packages []scanner.Package) ([]scanner.Package, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("%w: could not get current directory", err)
+	}
+	changed, err := affected.ChangedFiles(cwd, c.Since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the files changed since %s: %w", c.Since, err)
+	}
+	log.Printf("%d files changed since %s", len(changed), c.Since)
+
+	paths := make([]string, len(packages))
+	for i, pkg := range packages {
+		paths[i] = pkg.ImportPath
+	}
+	args, env := c.buildArgs()
+	deps, err := scanner.ListDependencies(paths, env, args...)
+	if err != nil {
+		log.Printf("Warning: Failed to list the imports of the packages, testing all of them: %v", err)
+		return packages, nil
+	}
+	reasons := affected.NewGraph(deps).Affected(changed)
+
+	selected := make([]scanner.Package, 0, len(packages))
+	for _, pkg := range packages {
+		if _, ok := reasons[pkg.ImportPath]; ok {
+			log.Printf("Affected package %s: %s", pkg.ImportPath, affected.Explain(reasons, pkg.ImportPath))
+			selected = append(selected, pkg)
+		}
+	}
+	log.Printf("Selected %d packages affected by the changes, %d packages not affected", len(selected), len(packages)-len(selected))
+	return selected, nil
+}
@@ -0,0 +1,15 @@
+package command
+
+import (
+	"github.com/alecthomas/kong"
+)
+
+// App is the root of the command line interface
+type App struct {
+	Split    CLI         `cmd:"" default:"withargs" help:"Generate test scripts split across nodes (default command)"`
+	Timings  TimingsCmd  `cmd:"" help:"Manage the timing database"`
+	Flaky    FlakyCmd    `cmd:"" help:"Report flaky tests from the results of previous runs"`
+	Accuracy AccuracyCmd `cmd:"" help:"Compare the predicted durations of the nodes with the actual results"`
+
+	Version kong.VersionFlag `short:"v" long:"version" help:"Print version and exit"`
+}
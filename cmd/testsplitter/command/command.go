@@ -2,73 +2,103 @@
 package command
 
 import (
-	"bufio"
+	"cmp"
 	"fmt"
-	"io/fs"
 	"iter"
 	"log"
 	"maps"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
 	"slices"
+	"strconv"
 	"strings"
 	"text/template"
 	"time"
 
-	"github.com/alecthomas/kong"
 	"github.com/sourcegraph/conc/pool"
-	"github.com/takuo/go-testsplitter/internal/parser"
+	"github.com/takuo/go-testsplitter/internal/costmodel"
+	"github.com/takuo/go-testsplitter/internal/estimate"
+	"github.com/takuo/go-testsplitter/internal/history"
+	"github.com/takuo/go-testsplitter/internal/plan"
 	"github.com/takuo/go-testsplitter/internal/scanner"
 	"github.com/takuo/go-testsplitter/internal/templates"
+	"github.com/takuo/go-testsplitter/internal/testpattern"
 	"github.com/takuo/go-testsplitter/internal/types"
 	"github.com/takuo/go-testsplitter/pkg/durchunk"
 )
 
+// groupPrefix prefixes the scheduling units of the groups given by //testsplitter:group
+const groupPrefix = "@group:"
+
 // CLI main command line interface
 type CLI struct {
-	Nodes        int      `short:"n" long:"nodes" required:"" default:"4" help:"Number of nodes"`
-	Concurrency  int      `short:"c" long:"concurrency" default:"4" help:"Number of concurrent test executions per node"`
-	ScriptsDir   string   `short:"o" long:"scripts-dir" required:"" default:"./test-scripts" help:"Directory to output generated scripts"`
-	ScanPackages bool     `short:"s" long:"scan-packages" help:"Scan Go packages from the current directory (like 'go list'). If not specified, package list is read from stdin."`
-	Exclude      string   `short:"x" long:"exclude" help:"Regex pattern to exclude packages (used only with --scan-packages)"`
-	JSONDir      string   `short:"j" long:"json-dir" default:"./test-json" help:"Directory containing go test -json results"`
-	Template     string   `short:"t" long:"template" help:"Path to the template file (optional)"`
-	MaxFunctions int      `short:"m" long:"max-functions" default:"0" help:"Maximum number of test functions per package (0: unlimited)"`
-	TestFlags    []string `arg:"" help:"Flags to pass to the test binary after --" optional:""`
+	Nodes       int    `short:"n" long:"nodes" required:"" default:"4" help:"Number of nodes"`
+	Concurrency int    `short:"c" long:"concurrency" default:"4" help:"Number of concurrent test executions per node"`
+	ScriptsDir  string `short:"o" long:"scripts-dir" required:"" default:"./test-scripts" help:"Directory to output generated scripts"`
+
+	PackageFlags  `embed:""`
+	AffectedFlags `embed:""`
+
+	Template         string        `short:"t" long:"template" help:"Path to the template file (optional)"`
+	MaxFunctions     int           `short:"m" long:"max-functions" default:"0" help:"Maximum number of test functions per package (0: unlimited)"`
+	SubtestThreshold time.Duration `long:"subtest-threshold" default:"0" help:"Split tests taking longer than this into parts running groups of their subtests (0: disabled)"`
+	TestParallel     int           `long:"test-parallel" default:"0" help:"Number of t.Parallel() tests run at once on the nodes (0: -test.parallel from the test flags, or GOMAXPROCS)"`
+	Ginkgo           bool          `long:"ginkgo" help:"Split Ginkgo v2 suites by specs, listed with a dry run of the test binaries"`
+	Unknown          string        `short:"u" long:"unknown-estimator" enum:"fixed,package-mean,package-median,file,size" default:"fixed" help:"How to estimate tests without history (fixed, package-mean, package-median, file, size)"`
+	DefaultDur       time.Duration `long:"default-duration" default:"5s" help:"Duration of tests without history for the fixed estimator and as the last resort of the others"`
+	Seed             int64         `long:"seed" default:"0" help:"Seed of the randomized split, to generate the same scripts from the same input (0: random)"`
+	TestFlags        []string      `arg:"" help:"Flags to pass to the test binary after --" optional:""`
+
+	HistoryFlags `embed:""`
 
 	BinariesDir      string `short:"p" long:"binaries-dir" default:"./test-bin" help:"Directory to output or containing test binaries"`
 	BuildConcurrency int    `short:"b" long:"build-concurrency" default:"4" help:"Concurrency for building test binaries"`
 	DisableBuild     bool   `short:"d" long:"disable-build" default:"false" help:"Disable building test binaries (use pre-built binaries by other way)"`
 
-	Version kong.VersionFlag `short:"v" long:"version" help:"Print version and exit"`
+	BuildFlags `embed:""`
+
+	ScanFlags `embed:""`
+
+	LabelFlags `embed:""`
+
+	FilterFlags `embed:""`
 
 	// Runtime context
-	packages      []string                  `kong:"-"`
-	testFunctions map[string][]string       `kong:"-"`
-	testDurations map[string]time.Duration  `kong:"-"`
-	testInfos     []types.TestInfo          `kong:"-"`
-	nodeTests     iter.Seq[*types.NodeTest] `kong:"-"`
-	template      string                    `kong:"-"`
+	packages      packageList                   `kong:"-"`
+	testFunctions map[string][]string           `kong:"-"`
+	tests         map[string][]scanner.TestFunc `kong:"-"`
+	testFuncs     map[string]scanner.TestFunc   `kong:"-"`
+	history       history.History               `kong:"-"`
+	testDurations map[string]time.Duration      `kong:"-"`
+	overheads     map[string]time.Duration      `kong:"-"`
+	testInfos     []types.TestInfo              `kong:"-"`
+	nodeTests     iter.Seq[*types.NodeTest]     `kong:"-"`
+	plan          *plan.Plan                    `kong:"-"`
+	selector      *labelSelector                `kong:"-"`
+	filter        *testFilter                   `kong:"-"`
+	template      string                        `kong:"-"`
 }
 
-func (c *CLI) scanPackages() (err error) {
-	// Get packages either from scan directory or stdin
-	if c.ScanPackages {
-		if c.packages, err = scanner.ScanPackages(c.Exclude); err != nil {
-			return fmt.Errorf("failed to scan packages: %v", err)
-		}
-	} else {
-		if err = c.readPackagesFromStdin(); err != nil {
-			return fmt.Errorf("failed to read packages from stdin: %v", err)
-		}
-		log.Printf("Read %d packages from stdin: %v", len(c.packages), c.packages)
+func (c *CLI) scanPackages() error {
+	packages, err := c.listPackages()
+	if err == nil && c.Since != "" {
+		packages, err = c.affectedPackages(packages)
 	}
-	return nil
+	c.packages = newPackageList(packages)
+	return err
 }
 
 // Run run the command line
 func (c *CLI) Run() error {
+	var err error
+	if c.selector, err = c.labelSelector(); err != nil {
+		return err
+	}
+	if c.filter, err = c.testFilter(); err != nil {
+		return err
+	}
 	if err := c.scanPackages(); err != nil {
 		return fmt.Errorf("failed to scan packages from %s: %v", ".", err)
 	}
@@ -88,7 +118,17 @@ func (c *CLI) Run() error {
 	}
 
 	// Create test info with durations
-	c.createTestInfos()
+	if err := c.createTestInfos(); err != nil {
+		return fmt.Errorf("failed to estimate test durations: %w", err)
+	}
+
+	// Select tests by their names and labels
+	c.filterTests()
+	c.selectTests()
+
+	// Split long tests into parts by their subtests
+	c.splitSubtests()
+	c.splitGinkgoSuites()
 
 	// Split tests across nodes
 	c.splitTests()
@@ -119,86 +159,247 @@ func (c *CLI) loadTemplate() (err error) {
 	return
 }
 
-func (c *CLI) readPackagesFromStdin() (err error) {
-	c.packages = []string{} // initialize packages slice
-	scanner := bufio.NewScanner(os.Stdin)
-	for scanner.Scan() {
-		pkg := strings.TrimSpace(scanner.Text())
-		if pkg != "" {
-			c.packages = append(c.packages, pkg)
-		}
+func (c *CLI) scanTestFunctions() error {
+	tests, err := c.packages.scan(c.buildContext(), &c.ScanFlags)
+	if err != nil {
+		return err
 	}
-	return scanner.Err()
+	c.setTests(tests)
+	return nil
 }
 
-func (c *CLI) scanTestFunctions() (err error) {
-	c.testFunctions, err = scanner.ScanTestFunctions(c.packages)
-	return
+// setTests stores the scanned test functions and indexes them by their keys
+func (c *CLI) setTests(tests map[string][]scanner.TestFunc) {
+	c.tests = tests
+	c.testFunctions = make(map[string][]string, len(tests))
+	c.testFuncs = make(map[string]scanner.TestFunc)
+	for pkg, fns := range tests {
+		for _, fn := range fns {
+			c.testFunctions[pkg] = append(c.testFunctions[pkg], fn.Name)
+			c.testFuncs[history.Key(pkg, fn.Name)] = fn
+		}
+	}
 }
 
 func (c *CLI) loadTestDurations() (err error) {
-	var files int
-
 	c.testDurations = make(map[string]time.Duration)
-
-	err = filepath.WalkDir(c.JSONDir, func(path string, _ fs.DirEntry, err error) error {
-		if err != nil {
-			return nil // Skip files that can't be accessed
+	est, err := history.NewEstimator(c.Estimator, c.HalfLife)
+	if err != nil {
+		return err
+	}
+	db, err := c.loadHistory()
+	if db != nil {
+		c.history = db.Tests
+		// results of older scripts are recorded by directory, Ginkgo reports by absolute directory
+		if n := c.history.RenamePackages(c.packages.recordedAliases(c.history)); n > 0 {
+			log.Printf("Matched %d testcases recorded by directory to their import paths\n", n)
+		}
+		renameFingerprintPackages(db.Fingerprints, c.packages.aliases)
+		c.carryOverRenames(db.Fingerprints)
+		c.testDurations = c.history.Durations(est)
+	}
+	c.overheads = make(map[string]time.Duration)
+	for key, d := range c.testDurations {
+		if history.IsPackageKey(key) {
+			pkg, _ := history.SplitKey(key)
+			c.overheads[pkg] = d
 		}
+	}
+	if len(c.overheads) > 0 {
+		log.Printf("Loaded package overheads of %d packages\n", len(c.overheads))
+	}
+	return err
+}
+
+// testUnit returns the details of a scanned test function for estimation
+func (c *CLI) testUnit(pkg, fn string) estimate.Unit {
+	tf := c.testFunc(pkg, fn)
+	return estimate.Unit{Package: pkg, Function: fn, File: tf.File, Stmts: tf.Stmts}
+}
+
+// isParallel reports whether the test calls t.Parallel(), either seen in the
+// previous results or found in the source
+func (c *CLI) isParallel(pkg, fn string) bool {
+	return c.history.Parallel(history.Key(pkg, fn)) || c.testFunc(pkg, fn).Parallel
+}
+
+// testFunc returns the scanned test function, or the zero value if not found
+func (c *CLI) testFunc(pkg, fn string) scanner.TestFunc {
+	return c.testFuncs[history.Key(pkg, fn)]
+}
 
-		switch filepath.Ext(path) {
-		case ".jsonl", ".json":
-		default:
-			return nil
+// testParallel returns the -test.parallel value used on the nodes
+func (c *CLI) testParallel() int {
+	if c.TestParallel > 0 {
+		return c.TestParallel
+	}
+	for i, flag := range c.TestFlags {
+		name, value, ok := strings.Cut(strings.TrimPrefix(flag, "-"), "=")
+		if name != "-test.parallel" && name != "test.parallel" {
+			continue
+		}
+		if !ok && i+1 < len(c.TestFlags) {
+			value = c.TestFlags[i+1]
 		}
+		if n, err := strconv.Atoi(value); err == nil {
+			return n
+		}
+	}
+	return runtime.GOMAXPROCS(0)
+}
 
-		fp, err := os.OpenFile(path, os.O_RDONLY, 0)
-		if err != nil {
-			log.Printf("Failed to read %s: %v\n", path, err)
-			return nil // Skip files that can't be read
+// newDurationEstimator creates the estimator for tests without history from the known ones
+func (c *CLI) newDurationEstimator() (estimate.DurationEstimator, error) {
+	var known []estimate.Known
+	for pkg, functions := range c.testFunctions {
+		for _, fn := range functions {
+			if d, ok := c.testDurations[history.Key(pkg, fn)]; ok {
+				known = append(known, estimate.Known{Unit: c.testUnit(pkg, fn), Duration: d})
+			}
 		}
-		defer fp.Close()
-		data := parser.ParseGoTestJSONL(bufio.NewScanner(fp))
-		files++
-		maps.Copy(c.testDurations, data)
-		return nil
-	})
-	log.Printf("Loaded %d testcases durations from %d files in %s\n", len(c.testDurations), files, c.JSONDir)
-	return err
+	}
+	return estimate.New(c.Unknown, known, c.DefaultDur)
 }
 
-func (c *CLI) createTestInfos() {
+func (c *CLI) createTestInfos() error {
 	c.testInfos = []types.TestInfo{}
 
+	estimator, err := c.newDurationEstimator()
+	if err != nil {
+		return err
+	}
+
+	var estimated int
 	for pkg, functions := range c.testFunctions {
 		for _, fn := range functions {
-			key := fmt.Sprintf("%s:%s", pkg, fn)
-			duration := c.testDurations[key]
-			if duration == 0 {
-				// Default duration for unknown tests
-				duration = 5 * time.Second
+			d := c.testFunc(pkg, fn).Directives
+			duration, ok := c.testDurations[history.Key(pkg, fn)]
+			switch {
+			case d.Weight > 0:
+				duration = d.Weight
+			case !ok:
+				duration = estimator.Estimate(c.testUnit(pkg, fn))
+				estimated++
 			}
 
 			c.testInfos = append(c.testInfos, types.TestInfo{
 				Package:  pkg,
 				Function: fn,
 				Duration: duration,
+				Parallel: c.isParallel(pkg, fn),
+				Isolate:  d.Isolate,
+				Node:     d.Node,
+				Pinned:   d.Pinned,
+				Group:    d.Group,
 			})
 		}
 	}
+	if estimated > 0 {
+		log.Printf("Estimated durations of %d tests without history (%s)\n", estimated, cmp.Or(c.Unknown, estimate.StrategyFixed))
+	}
+	return nil
 }
 
 func (c *CLI) splitTests() {
-	var dataSeq iter.Seq2[string, time.Duration]
-	dataSeq = func(yield func(k string, d time.Duration) bool) {
-		for _, test := range c.testInfos {
-			key := fmt.Sprintf("%s:%s", test.Package, test.Function)
-			if !yield(key, test.Duration) {
-				return
+	model := &costmodel.Model{
+		Durations:    make(map[string]time.Duration, len(c.testInfos)),
+		Overheads:    c.overheads,
+		Standalone:   make(map[string]bool),
+		Parallel:     make(map[string]bool),
+		MaxFunctions: c.MaxFunctions,
+		TestParallel: c.testParallel(),
+	}
+	tests := make(map[string]types.TestInfo, len(c.testInfos))
+	// tests of a group are scheduled together as a single unit
+	units := make(map[string][]string, len(c.testInfos))
+	pinned := make(map[string]int)
+	for _, test := range c.testInfos {
+		key := test.Key()
+		tests[key] = test
+		model.Durations[key] = test.Duration
+		model.Parallel[key] = test.Parallel
+		if test.IsPartial() || test.Isolate {
+			model.Standalone[key] = true
+		}
+		unit := key
+		if test.Group != "" {
+			unit = groupPrefix + test.Group
+		}
+		units[unit] = append(units[unit], key)
+		if !test.Pinned {
+			continue
+		}
+		if test.Node >= c.Nodes {
+			log.Printf("Warning: %s is pinned to node %d but there are only %d nodes\n", key, test.Node, c.Nodes)
+			continue
+		}
+		if node, ok := pinned[unit]; ok && node != test.Node {
+			log.Printf("Warning: %s is pinned to node %d but its group runs on node %d\n", key, test.Node, node)
+			continue
+		}
+		pinned[unit] = test.Node
+	}
+	durations := make(map[string]time.Duration, len(units))
+	for unit, keys := range units {
+		// the tests come in the random order of the map of the packages
+		slices.Sort(keys)
+		for _, key := range keys {
+			durations[unit] += model.Durations[key]
+		}
+	}
+	// expand returns the keys of the tests in the units
+	expand := func(unitKeys []string) []string {
+		var keys []string
+		for _, unit := range unitKeys {
+			keys = append(keys, units[unit]...)
+		}
+		return keys
+	}
+	// the cost of a node is the sum of the costs of its packages, so moving a
+	// unit recomputes only the packages of its tests
+	packages := make(map[string][]string, len(units))
+	for unit, keys := range units {
+		for _, key := range keys {
+			if pkg := tests[key].Package; !slices.Contains(packages[unit], pkg) {
+				packages[unit] = append(packages[unit], pkg)
 			}
 		}
 	}
-	chunks := durchunk.SplitBalanced(dataSeq, c.Nodes)
+	var buf []string
+	cost := func(pkg string, unitKeys []string) time.Duration {
+		buf = buf[:0]
+		for _, unit := range unitKeys {
+			for _, key := range units[unit] {
+				if tests[key].Package == pkg {
+					buf = append(buf, key)
+				}
+			}
+		}
+		return model.PackageCost(pkg, buf)
+	}
+	groups := func(unit string) []string { return packages[unit] }
+
+	opts := []durchunk.Option{durchunk.WithGroupCost(groups, cost), durchunk.WithPinned(pinned)}
+	if c.Seed != 0 {
+		opts = append(opts, durchunk.WithSeed(c.Seed))
+	}
+	chunks := durchunk.SplitBalanced(maps.All(durations), c.Nodes, opts...)
+	c.plan = &plan.Plan{Created: time.Now().UTC(), MaxFunctions: model.MaxFunctions, TestParallel: model.TestParallel}
+	for i, chunk := range chunks {
+		node := plan.Node{Index: i, Predicted: chunk.Total.Seconds(), Tests: []plan.Test{}}
+		for _, key := range slices.Sorted(slices.Values(expand(chunk.Keys))) {
+			test := tests[key]
+			node.Tests = append(node.Tests, plan.Test{
+				Package:    test.Package,
+				Function:   test.Function,
+				Part:       test.Part,
+				Predicted:  test.Duration.Seconds(),
+				Parallel:   test.Parallel,
+				Standalone: model.Standalone[key],
+			})
+		}
+		c.plan.Nodes = append(c.plan.Nodes, node)
+	}
 	c.nodeTests = func(yield func(*types.NodeTest) bool) {
 		for i, chunk := range chunks {
 			nt := &types.NodeTest{
@@ -207,10 +408,16 @@ func (c *CLI) splitTests() {
 				Flags:         strings.Join(c.TestFlags, " "),
 				TotalDuration: chunk.Total,
 			}
-			for _, key := range chunk.Keys {
-				s := strings.Index(key, ":")
-				pkg, fn := key[:s], key[s+1:]
-				nt.Funcs[pkg] = append(nt.Funcs[pkg], fn)
+			for _, key := range expand(chunk.Keys) {
+				test := tests[key]
+				switch {
+				case test.Isolate:
+					nt.Isolated = append(nt.Isolated, test)
+				case test.IsPartial():
+					nt.Partials = append(nt.Partials, test)
+				default:
+					nt.Funcs[test.Package] = append(nt.Funcs[test.Package], test.Function)
+				}
 			}
 			if !yield(nt) {
 				return
@@ -245,14 +452,35 @@ func (c *CLI) generateScriptFiles() error {
 		}
 		defer file.Close()
 
+		path, err := filepath.Abs(c.BinariesDir)
+		if err != nil {
+			return fmt.Errorf("failed to get absolute binary path: %w", err)
+		}
+		JSONDir, err := filepath.Abs(c.JSONDir)
+		if err != nil {
+			return fmt.Errorf("failed to get absolute JSON directory: %w", err)
+		}
+
 		// Prepare template data
-		linesSeq := func(yield func(tl types.TestLine) bool) {
-			for pkg, funcs := range nt.Funcs {
+		linesSeq := func(yieldLine func(tl types.TestLine) bool) {
+			n := 0
+			yield := func(tl types.TestLine) bool {
+				tl.Package = c.packages.dir(tl.ImportPath)
+				n++
+				if c.Ginkgo && c.hasGinkgoSuite(tl.ImportPath) {
+					// each line writes its own report of the spec durations into -j
+					report := filepath.Join(JSONDir, fmt.Sprintf("ginkgo-%d-%d.json", nt.NodeIndex, n))
+					tl.Args = strings.TrimSpace(tl.Args + " -ginkgo.json-report=" + report)
+				}
+				return yieldLine(tl)
+			}
+			for _, pkg := range slices.Sorted(maps.Keys(nt.Funcs)) {
+				funcs := nt.Funcs[pkg]
 				if c.MaxFunctions > 0 {
 					for funcs := range slices.Chunk(funcs, c.MaxFunctions) {
 						if !yield(types.TestLine{
-							Package:     pkg,
-							TestPattern: "^(" + strings.Join(funcs, "|") + ")$",
+							ImportPath:  pkg,
+							TestPattern: testpattern.Run(funcs),
 							Flags:       nt.Flags,
 						}) {
 							return
@@ -260,24 +488,28 @@ func (c *CLI) generateScriptFiles() error {
 					}
 				} else {
 					if !yield(types.TestLine{
-						Package:     pkg,
-						TestPattern: "^(" + strings.Join(funcs, "|") + ")$",
+						ImportPath:  pkg,
+						TestPattern: testpattern.Run(funcs),
 						Flags:       nt.Flags,
 					}) {
 						return
 					}
 				}
 			}
+			for _, test := range nt.Partials {
+				if !yield(partialTestLine(&test, nt.Flags)) {
+					return
+				}
+			}
+			for _, test := range nt.Isolated {
+				tl := partialTestLine(&test, nt.Flags)
+				tl.Isolate = true
+				if !yield(tl) {
+					return
+				}
+			}
 		}
 
-		path, err := filepath.Abs(c.BinariesDir)
-		if err != nil {
-			return fmt.Errorf("failed to get absolute binary path: %w", err)
-		}
-		JSONDir, err := filepath.Abs(c.JSONDir)
-		if err != nil {
-			return fmt.Errorf("failed to get absolute JSON directory: %w", err)
-		}
 		templateData := types.TemplateData{
 			NodeIndex:   nt.NodeIndex,
 			Concurrency: c.Concurrency,
@@ -298,13 +530,19 @@ func (c *CLI) generateScriptFiles() error {
 		}
 	}
 
+	// Save the assignment for `testsplitter accuracy`
+	if c.plan != nil {
+		if err := plan.Save(filepath.Join(c.ScriptsDir, plan.FileName), c.plan); err != nil {
+			return fmt.Errorf("failed to save plan: %w", err)
+		}
+	}
 	return nil
 }
 
 // BuildTestBinaries builds test binaries for all target packages into the output directory.
 // The binary name is generated by replacing "/" with "." and appending ".test".
 func (c *CLI) buildTestBinaries() error {
-	log.Printf("Building test binaries for %d packages with concurrency %d.\n", len(c.packages), c.BuildConcurrency)
+	log.Printf("Building test binaries for %d packages with concurrency %d.\n", len(c.packages.paths), c.BuildConcurrency)
 	p := pool.New().WithErrors().WithMaxGoroutines(c.BuildConcurrency)
 
 	outputPath, err := filepath.Abs(c.BinariesDir)
@@ -315,15 +553,22 @@ func (c *CLI) buildTestBinaries() error {
 	if err != nil {
 		return fmt.Errorf("failed to get current working directory: %w", err)
 	}
+	buildArgs, buildEnv := c.buildArgs()
 	// 例: api/service/foo → api.service.foo.test
-	for _, pkg := range c.packages {
+	for _, pkg := range c.packages.paths {
 		p.Go(func() error {
-			binName := strings.ReplaceAll(pkg, "/", ".") + ".test"
-			outputPath := filepath.Join(outputPath, binName)
+			outputPath := filepath.Join(outputPath, binaryName(pkg))
 			log.Printf("Building %s as %s...\n", pkg, outputPath)
 			outputPath, _ = filepath.Abs(outputPath)
-			cmd := exec.Command("go", "test", "-c", "-o", outputPath, ".")
-			cmd.Dir = filepath.Join(cwd, pkg)
+			args := append([]string{"test", "-c", "-o", outputPath}, buildArgs...)
+			cmd := exec.Command("go", append(args, ".")...)
+			cmd.Dir = c.packages.dir(pkg)
+			if !filepath.IsAbs(cmd.Dir) {
+				cmd.Dir = filepath.Join(cwd, cmd.Dir)
+			}
+			if len(buildEnv) > 0 {
+				cmd.Env = append(os.Environ(), buildEnv...)
+			}
 			output, err := cmd.CombinedOutput()
 			if len(output) > 0 {
 				fmt.Println(string(output))
@@ -5,13 +5,17 @@ import (
 	"path/filepath"
 	"slices"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/takuo/go-testsplitter/internal/history"
+	"github.com/takuo/go-testsplitter/internal/hygiene"
 	"github.com/takuo/go-testsplitter/internal/scanner"
+	"github.com/takuo/go-testsplitter/internal/testpattern"
 	"github.com/takuo/go-testsplitter/internal/types"
 )
 
@@ -59,6 +63,7 @@ func helperFunction() {
 
 func TestCreateTestInfos(t *testing.T) {
 	cli := &CLI{
+		DefaultDur: 5 * time.Second,
 		testFunctions: map[string][]string{
 			"pkg1": {"TestA", "TestB"},
 			"pkg2": {"TestC"},
@@ -82,6 +87,15 @@ func TestCreateTestInfos(t *testing.T) {
 			assert.Equal(t, 5*time.Second, info.Duration, "Should use default duration for TestB")
 		}
 	}
+
+	// an explicit --default-duration=0 is kept
+	cli.DefaultDur = 0
+	require.NoError(t, cli.createTestInfos())
+	for _, info := range cli.testInfos {
+		if info.Function == "TestB" {
+			assert.Zero(t, info.Duration)
+		}
+	}
 }
 
 func TestSplitTests(t *testing.T) {
@@ -195,6 +209,77 @@ func TestSplitTests_FunctionLevel(t *testing.T) {
 	assert.LessOrEqual(t, max-min, maxSingle, "Node total durations should be balanced (diff=%v, maxSingle=%v)", max-min, maxSingle)
 }
 
+func TestSplitTests_PackageOverhead(t *testing.T) {
+	cli := &CLI{
+		Nodes:        2,
+		MaxFunctions: 2,
+		testInfos: []types.TestInfo{
+			{Package: "pkg1", Function: "TestA", Duration: 1 * time.Second},
+			{Package: "pkg1", Function: "TestB", Duration: 1 * time.Second},
+			{Package: "pkg1", Function: "TestC", Duration: 1 * time.Second},
+			{Package: "pkg2", Function: "TestD", Duration: 20 * time.Second},
+		},
+		overheads: map[string]time.Duration{"pkg1": 8 * time.Second},
+	}
+
+	cli.splitTests()
+
+	for nt := range cli.nodeTests {
+		if fns, ok := nt.Funcs["pkg1"]; ok {
+			// pkg1 fits in one node with 2 invocations: 3s + 2*8s
+			assert.Len(t, fns, 3, "pkg1 should not be split across nodes")
+			assert.Equal(t, 19*time.Second, nt.TotalDuration, "overhead should be charged per invocation")
+		}
+	}
+}
+
+func TestSplitTests_Directives(t *testing.T) {
+	cli := &CLI{
+		Nodes: 3,
+		testInfos: []types.TestInfo{
+			{Package: "pkg1", Function: "TestA", Duration: 10 * time.Second, Group: "db"},
+			{Package: "pkg2", Function: "TestB", Duration: 10 * time.Second, Group: "db"},
+			{Package: "pkg1", Function: "TestC", Duration: 1 * time.Second, Node: 2, Pinned: true},
+			{Package: "pkg1", Function: "TestD", Duration: 1 * time.Second, Node: 5, Pinned: true},
+			{Package: "pkg3", Function: "TestE", Duration: 15 * time.Second, Isolate: true},
+			{Package: "pkg3", Function: "TestF", Duration: 5 * time.Second},
+		},
+	}
+
+	cli.splitTests()
+
+	nodeOf := make(map[string]int)
+	for nt := range cli.nodeTests {
+		for pkg, fns := range nt.Funcs {
+			for _, fn := range fns {
+				nodeOf[pkg+":"+fn] = nt.NodeIndex
+			}
+		}
+		for _, test := range nt.Isolated {
+			assert.Equal(t, "pkg3:TestE", test.Key())
+			nodeOf[test.Key()] = nt.NodeIndex
+		}
+	}
+	assert.Len(t, nodeOf, 6, "every test should be assigned once")
+	assert.Equal(t, nodeOf["pkg1:TestA"], nodeOf["pkg2:TestB"], "tests of a group should run on the same node")
+	assert.Equal(t, 2, nodeOf["pkg1:TestC"], "pinned test should run on its node")
+}
+
+func TestCreateTestInfos_Directives(t *testing.T) {
+	cli := &CLI{
+		testDurations: map[string]time.Duration{"pkg1:TestA": time.Second},
+	}
+	cli.setTests(map[string][]scanner.TestFunc{
+		"pkg1": {{Name: "TestA", Directives: scanner.Directives{Weight: time.Minute, Isolate: true, Group: "db"}}},
+	})
+
+	require.NoError(t, cli.createTestInfos())
+
+	assert.Equal(t, []types.TestInfo{
+		{Package: "pkg1", Function: "TestA", Duration: time.Minute, Isolate: true, Group: "db"},
+	}, cli.testInfos, "weight should override the history")
+}
+
 func TestGenerateScriptFiles(t *testing.T) {
 	cli := &CLI{
 		Nodes:      2,
@@ -235,3 +320,381 @@ func TestGenerateScriptFiles(t *testing.T) {
 		assert.Contains(t, contentStr, "set -e", "Script should contain set -e")
 	}
 }
+
+func TestSplitSubtests(t *testing.T) {
+	cli := &CLI{
+		Nodes:            2,
+		SubtestThreshold: 5 * time.Second,
+		ScriptsDir:       t.TempDir(),
+		testInfos: []types.TestInfo{
+			{Package: "pkg1", Function: "TestTable", Duration: 13 * time.Second},
+			{Package: "pkg1", Function: "TestSmall", Duration: 1 * time.Second},
+		},
+		testDurations: map[string]time.Duration{
+			"pkg1:TestTable":            13 * time.Second,
+			"pkg1:TestTable/case_a":     4 * time.Second,
+			"pkg1:TestTable/case_b":     4 * time.Second,
+			"pkg1:TestTable/case_c":     4 * time.Second,
+			"pkg1:TestTable/case_c/sub": 2 * time.Second,
+			"pkg1:TestSmall":            1 * time.Second,
+		},
+	}
+
+	cli.splitSubtests()
+
+	var parts []types.TestInfo
+	for _, ti := range cli.testInfos {
+		if ti.Function == "TestTable" {
+			parts = append(parts, ti)
+		}
+	}
+	require.Len(t, parts, 3, "13s test should be split into 3 parts of at most 5s")
+	var subtests []string
+	for i, part := range parts {
+		assert.Equal(t, 5*time.Second, part.Duration, "each part pays the 1s spent in the parent")
+		if i < len(parts)-1 {
+			assert.Len(t, part.Subtests, 1)
+			subtests = append(subtests, part.Subtests...)
+		} else {
+			assert.Empty(t, part.Subtests)
+			assert.ElementsMatch(t, subtests, part.SkipSubtests, "last part runs all other subtests")
+		}
+	}
+
+	cli.splitTests()
+	cli.loadTemplate()
+	require.NoError(t, cli.generateScriptFiles())
+
+	var script string
+	for i := range cli.Nodes {
+		b, err := os.ReadFile(filepath.Join(cli.ScriptsDir, "test-node-"+strconv.Itoa(i)+".sh"))
+		require.NoError(t, err)
+		script += string(b)
+	}
+	for _, sub := range subtests {
+		assert.Contains(t, script, "pkg1 '^TestTable$/^("+sub+")$'\n")
+	}
+	assert.Contains(t, script, "pkg1 '^(TestTable)$' -test.skip '^TestTable$/^(")
+}
+
+func TestSplitBySubtests_NoDuration(t *testing.T) {
+	// e.g. a Ginkgo suite without history and --default-duration=0
+	test := types.TestInfo{Package: "pkg1", Function: "TestSuite"}
+	subs := subtestDurations(0, nil, []string{"spec a", "spec b"})
+	threshold := max(test.Duration/2, 1)
+	assert.Equal(t, []types.TestInfo{test}, splitBySubtests(test, subs, threshold))
+}
+
+func TestSplitSubtests_Suite(t *testing.T) {
+	cli := &CLI{
+		SubtestThreshold: 10 * time.Second,
+		testInfos: []types.TestInfo{
+			{Package: "pkg1", Function: "TestUserSuite", Duration: 20 * time.Second},
+		},
+		testDurations: map[string]time.Duration{
+			"pkg1:TestUserSuite":             20 * time.Second,
+			"pkg1:TestUserSuite/TestCreate":  6 * time.Second,
+			"pkg1:TestUserSuite/TestDelete":  4 * time.Second,
+			"pkg1:TestUserSuite/TestRemoved": 9 * time.Second,
+		},
+	}
+	cli.setTests(map[string][]scanner.TestFunc{
+		"pkg1": {{Name: "TestUserSuite", SuiteMethods: []string{"TestCreate", "TestDelete", "TestList", "TestUpdate"}}},
+	})
+
+	cli.splitSubtests()
+
+	require.Len(t, cli.testInfos, 2, "20s suite should be split into 2 parts of at most 10s")
+	var total time.Duration
+	var methods []string
+	for _, part := range cli.testInfos {
+		total += part.Duration
+		methods = append(methods, part.Subtests...)
+	}
+	assert.Equal(t, 20*time.Second, total, "methods without history are estimated as the mean of the others")
+	assert.Subset(t, []string{"TestCreate", "TestDelete", "TestList", "TestUpdate"}, methods, "only current methods are scheduled")
+	assert.Equal(t, methods, cli.testInfos[1].SkipSubtests)
+}
+
+func TestSplitGinkgoSuites(t *testing.T) {
+	pkg := t.TempDir()
+	binDir := t.TempDir()
+	// a fake test binary writing the dry-run report
+	require.NoError(t, os.WriteFile(filepath.Join(pkg, "report.json"), []byte(`[{"SpecReports": [
+		{"ContainerHierarchyTexts": ["Books"], "LeafNodeType": "It", "LeafNodeText": "a", "State": "passed"},
+		{"ContainerHierarchyTexts": ["Books"], "LeafNodeType": "It", "LeafNodeText": "b", "State": "passed"},
+		{"ContainerHierarchyTexts": ["Books"], "LeafNodeType": "It", "LeafNodeText": "c", "State": "passed"}
+	]}]`), 0o644))
+	binary := filepath.Join(binDir, strings.ReplaceAll(pkg, "/", ".")+".test")
+	require.NoError(t, os.WriteFile(binary, []byte(`#!/bin/sh
+for arg in "$@"; do
+  case "$arg" in
+    -ginkgo.json-report=*) cp report.json "${arg#-ginkgo.json-report=}" ;;
+  esac
+done
+`), 0o755))
+
+	cli := &CLI{
+		Nodes:       2,
+		Ginkgo:      true,
+		BinariesDir: binDir,
+		testInfos: []types.TestInfo{
+			{Package: pkg, Function: "TestBooks", Duration: 9 * time.Second},
+		},
+		testDurations: map[string]time.Duration{
+			pkg + ":(ginkgo)/Books a": 5 * time.Second,
+			pkg + ":(ginkgo)/Books b": 3 * time.Second,
+		},
+	}
+	cli.setTests(map[string][]scanner.TestFunc{
+		pkg:     {{Name: "TestBooks", Ginkgo: true}},
+		"other": {{Name: "TestOther"}},
+	})
+
+	cli.splitGinkgoSuites()
+
+	require.Len(t, cli.testInfos, 2, "suite should be split by the number of nodes")
+	first, last := cli.testInfos[0], cli.testInfos[1]
+	assert.True(t, first.Ginkgo)
+	assert.Equal(t, first.Subtests, last.SkipSubtests)
+
+	tl := partialTestLine(&first, "")
+	assert.Equal(t, "^(TestBooks)$", tl.TestPattern)
+	assert.Equal(t, "-ginkgo.focus '"+testpattern.Exact(first.Subtests)+"'", tl.Args)
+	tl = partialTestLine(&last, "")
+	assert.Equal(t, "-ginkgo.skip '"+testpattern.Exact(first.Subtests)+"'", tl.Args)
+
+	// only the lines of the Ginkgo suite write their own reports
+	cli.testInfos = append(cli.testInfos, types.TestInfo{Package: "other", Function: "TestOther", Duration: time.Second})
+	cli.Nodes, cli.ScriptsDir, cli.JSONDir = 1, t.TempDir(), t.TempDir()
+	cli.splitTests()
+	require.NoError(t, cli.loadTemplate())
+	require.NoError(t, cli.generateScriptFiles())
+	content, err := os.ReadFile(filepath.Join(cli.ScriptsDir, "test-node-0.sh"))
+	require.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(string(content), " -ginkgo.json-report="+cli.JSONDir+"/ginkgo-0-"))
+	assert.Contains(t, string(content), "other other '^(TestOther)$'\n")
+}
+
+func TestTestParallel(t *testing.T) {
+	assert.Equal(t, 3, (&CLI{TestParallel: 3, TestFlags: []string{"-test.parallel=8"}}).testParallel())
+	assert.Equal(t, 8, (&CLI{TestFlags: []string{"-test.v", "-test.parallel=8"}}).testParallel())
+	assert.Equal(t, 2, (&CLI{TestFlags: []string{"-test.parallel", "2"}}).testParallel())
+}
+
+func TestSelectTests(t *testing.T) {
+	newCLI := func(flags LabelFlags) *CLI {
+		cli := &CLI{
+			LabelFlags: flags,
+		}
+		cli.setTests(map[string][]scanner.TestFunc{
+			"pkg1":     {{Name: "TestA"}, {Name: "TestSlowB"}, {Name: "FuzzC"}},
+			"e2e/api":  {{Name: "TestD", Directives: scanner.Directives{Labels: []string{"smoke"}}}},
+			"e2e/auth": {{Name: "TestE", Tags: []string{"integration"}}},
+		})
+		for pkg, fns := range cli.tests {
+			for _, fn := range fns {
+				cli.testInfos = append(cli.testInfos, types.TestInfo{Package: pkg, Function: fn.Name})
+			}
+		}
+		var err error
+		cli.selector, err = cli.labelSelector()
+		require.NoError(t, err)
+		return cli
+	}
+	selected := func(cli *CLI) []string {
+		cli.selectTests()
+		var keys []string
+		for _, test := range cli.testInfos {
+			keys = append(keys, test.Key())
+		}
+		return keys
+	}
+
+	rules := LabelFlags{LabelTest: []string{"slow=*Slow*"}, LabelPackage: []string{"e2e=e2e/..."}}
+
+	flags := rules
+	flags.Skip = "slow or fuzz"
+	assert.ElementsMatch(t, []string{"pkg1:TestA", "e2e/api:TestD", "e2e/auth:TestE"}, selected(newCLI(flags)))
+
+	flags = rules
+	flags.Select = "e2e and not (smoke or integration)"
+	assert.Empty(t, selected(newCLI(flags)))
+
+	flags = rules
+	flags.Select = "smoke or integration"
+	assert.ElementsMatch(t, []string{"e2e/api:TestD", "e2e/auth:TestE"}, selected(newCLI(flags)))
+
+	assert.Len(t, selected(newCLI(rules)), 5, "every test is selected without --select and --skip")
+
+	_, err := (&LabelFlags{Select: "e2e and"}).labelSelector()
+	assert.Error(t, err)
+}
+
+func TestFilterTests(t *testing.T) {
+	filtered := func(flags FilterFlags) []string {
+		cli := &CLI{FilterFlags: flags}
+		for _, key := range []string{"pkg1:TestA", "pkg1:TestAB", "pkg1:FuzzA", "pkg2:ExampleA", "pkg2:TestB"} {
+			pkg, fn, _ := strings.Cut(key, ":")
+			cli.testInfos = append(cli.testInfos, types.TestInfo{Package: pkg, Function: fn})
+		}
+		var err error
+		cli.filter, err = cli.testFilter()
+		require.NoError(t, err)
+		cli.filterTests()
+		var keys []string
+		for _, test := range cli.testInfos {
+			keys = append(keys, test.Key())
+		}
+		return keys
+	}
+
+	// like go test, the patterns are not anchored
+	assert.Equal(t, []string{"pkg1:TestA", "pkg1:TestAB", "pkg1:FuzzA", "pkg2:ExampleA"}, filtered(FilterFlags{RunTests: "A"}))
+	assert.Equal(t, []string{"pkg1:TestA", "pkg2:TestB"}, filtered(FilterFlags{RunTests: "^Test", SkipTests: "AB$"}))
+	assert.Equal(t, []string{"pkg1:TestA", "pkg1:TestAB", "pkg2:TestB"}, filtered(FilterFlags{SkipTests: "^(Fuzz|Example)"}))
+	assert.Equal(t, []string{"pkg1:TestA", "pkg2:TestB"}, filtered(FilterFlags{RunTests: "^(TestA|TestB)$"}))
+	assert.Len(t, filtered(FilterFlags{}), 5)
+
+	for _, flags := range []FilterFlags{{RunTests: "TestA/sub"}, {RunTests: "TestB|TestA/sub"}, {SkipTests: "(TestA|TestB)/sub"}, {RunTests: "Test("}} {
+		_, err := flags.testFilter()
+		assert.Error(t, err, flags)
+	}
+	// slashes in brackets don't separate levels
+	_, err := (&FilterFlags{RunTests: "Test[/]"}).testFilter()
+	assert.NoError(t, err)
+}
+
+func TestListPackages_Filters(t *testing.T) {
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	_, err = w.WriteString("github.com/takuo/go-testsplitter/internal/labels\n../../../internal/history\ngithub.com/takuo/go-testsplitter/internal/hygiene\n")
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	stdin := os.Stdin
+	os.Stdin = r
+	t.Cleanup(func() { os.Stdin = stdin })
+
+	flags := PackageFlags{IncludePackages: "internal/(labels|history|hygiene)$", Exclude: "hygiene"}
+	packages, err := flags.listPackages()
+	require.NoError(t, err)
+	var paths []string
+	for _, pkg := range packages {
+		paths = append(paths, pkg.ImportPath)
+	}
+	assert.ElementsMatch(t, []string{"github.com/takuo/go-testsplitter/internal/labels", "github.com/takuo/go-testsplitter/internal/history"}, paths)
+
+	_, err = (&PackageFlags{IncludePackages: "("}).listPackages()
+	assert.Error(t, err)
+}
+
+func TestPackageList(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "foo_test.go"), []byte("package foo\n\nimport \"testing\"\n\nfunc TestFoo(t *testing.T) {}\n"), 0o644))
+
+	cli := &CLI{
+		Nodes:      1,
+		ScriptsDir: t.TempDir(),
+		packages: newPackageList([]scanner.Package{
+			{ImportPath: "example.com/mod/api/foo", Dir: dir, Module: "example.com/mod"},
+		}),
+	}
+	assert.Equal(t, map[string]string{filepath.ToSlash(dir): "example.com/mod/api/foo", "api/foo": "example.com/mod/api/foo"}, cli.packages.aliases)
+	cli.ScanCache = filepath.Join(t.TempDir(), "scan.json.gz")
+
+	// test functions are keyed by import path
+	require.NoError(t, cli.scanTestFunctions())
+	assert.Equal(t, map[string][]string{"example.com/mod/api/foo": {"TestFoo"}}, cli.testFunctions)
+	assert.FileExists(t, cli.ScanCache)
+
+	// the script runs the binary named after the import path in the directory
+	cli.testInfos = []types.TestInfo{{Package: "example.com/mod/api/foo", Function: "TestFoo", Duration: time.Second}}
+	cli.splitTests()
+	require.NoError(t, cli.loadTemplate())
+	require.NoError(t, cli.generateScriptFiles())
+	content, err := os.ReadFile(filepath.Join(cli.ScriptsDir, "test-node-0.sh"))
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "example.com/mod/api/foo "+dir+" '^(TestFoo)$'")
+	assert.Equal(t, "example.com.mod.api.foo.test", binaryName("example.com/mod/api/foo"))
+
+	// Ginkgo reports written in another checkout are recorded by absolute directory
+	aliases := cli.packages.recordedAliases(history.History{
+		"/ci/work/mod/api/foo:(ginkgo)/Books a": nil,
+		"/ci/work/mod/api/bar:(ginkgo)/Books a": nil,
+		"api/foo:TestFoo":                       nil,
+	})
+	assert.Equal(t, "example.com/mod/api/foo", aliases["/ci/work/mod/api/foo"])
+	assert.NotContains(t, aliases, "/ci/work/mod/api/bar")
+}
+
+func TestTimingsHygiene_BuildConstraints(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, header, fn string) {
+		t.Helper()
+		src := header + "package foo\n\nimport \"testing\"\n\nfunc " + fn + "(t *testing.T) {}\n"
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(src), 0o644))
+	}
+	write("foo_test.go", "", "TestFoo")
+	write("foo_windows_test.go", "", "TestWindows")
+	write("foo_integration_test.go", "//go:build integration\n\n", "TestIntegration")
+
+	cmd := &TimingsHygieneCmd{BuildFlags: BuildFlags{GOOS: "linux"}, ScanFlags: ScanFlags{ScanCache: "off"}}
+	packages := newPackageList([]scanner.Package{{ImportPath: "example.com/mod/foo", Dir: dir}})
+	tests, err := packages.scan(cmd.scanContext(), &cmd.ScanFlags)
+	require.NoError(t, err)
+
+	// the tests excluded by the build constraints are not orphaned
+	hist := history.History{}
+	for _, fn := range []string{"TestFoo", "TestWindows", "TestIntegration", "TestRemoved"} {
+		hist[history.Key("example.com/mod/foo", fn)] = []history.Sample{{Duration: time.Second}}
+	}
+	report := hygiene.Check(hist, nil, fingerprints(packages.paths, tests))
+	assert.Equal(t, []string{history.Key("example.com/mod/foo", "TestRemoved")}, report.Orphaned)
+	assert.Empty(t, report.Missing)
+}
+
+func TestLoadHistory_UnreadableDB(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "test-timings.db"), []byte("not gzip"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "test-0-1.jsonl"), []byte(`{"Action":"run","Package":"pkg","Test":"TestA"}
+{"Action":"pass","Package":"pkg","Test":"TestA","Elapsed":1}
+`), 0o644))
+
+	// the raw result files are still read
+	flags := ResultFlags{StoreFlags: StoreFlags{TimingsDB: filepath.Join(dir, "test-timings.db")}, JSONDir: dir}
+	db, err := flags.loadHistory()
+	assert.Error(t, err)
+	require.NotNil(t, db)
+	assert.Len(t, db.Tests["pkg:TestA"], 1)
+}
+
+func TestLoadHistoryDir(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		filepath.Join("run-100", "test-0-1.jsonl"): `{"Action":"run","Package":"pkg","Test":"TestA"}
+{"Action":"pass","Package":"pkg","Test":"TestA","Elapsed":1}
+`,
+		// the same run as test-0-1.jsonl
+		filepath.Join("run-100", "test-reports", "junit-0-1.xml"): `<testsuite name="pkg"><testcase classname="pkg" name="TestA" time="1"/></testsuite>`,
+		filepath.Join("run-100", "test-reports", "junit-1-1.xml"): `<testsuite name="pkg"><testcase classname="pkg" name="TestB" time="2"/></testsuite>`,
+		// another run with the same node and line, keeping only JUnit reports
+		filepath.Join("run-99", "test-reports", "junit-0-1.xml"): `<testsuite name="pkg"><testcase classname="pkg" name="TestC" time="3"/></testsuite>`,
+		// the JSONL results in the -j directory of the run
+		filepath.Join("run-101", "test-json", "test-0-1.jsonl"): `{"Action":"run","Package":"pkg","Test":"TestD"}
+{"Action":"pass","Package":"pkg","Test":"TestD","Elapsed":1}
+`,
+		filepath.Join("run-101", "test-reports", "junit-0-1.xml"): `<testsuite name="pkg"><testcase classname="pkg" name="TestD" time="1"/></testsuite>`,
+	}
+	for name, content := range files {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+
+	hist, n, err := loadHistoryDir(dir)
+	require.NoError(t, err)
+	assert.Equal(t, 4, n)
+	assert.Len(t, hist["pkg:TestA"], 1, "the JUnit report of a run with a JSONL result is skipped")
+	assert.Len(t, hist["pkg:TestB"], 1)
+	assert.Len(t, hist["pkg:TestC"], 1, "the JUnit reports of other runs are read")
+	assert.Len(t, hist["pkg:TestD"], 1)
+}
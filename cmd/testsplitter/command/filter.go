@@ -0,0 +1,112 @@
+package command
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+
+	"github.com/takuo/go-testsplitter/internal/types"
+)
+
+// FilterFlags select the tests to run by their names, like the -run and -skip
+// flags of go test. --skip-tests is named so as not to clash with --skip of the labels.
+type FilterFlags struct {
+	RunTests  string `name:"run" long:"run" placeholder:"REGEXP" help:"Run only the test functions, fuzz targets and examples whose name matches the regexp, like go test -run"`
+	SkipTests string `long:"skip-tests" placeholder:"REGEXP" help:"Skip the test functions, fuzz targets and examples whose name matches the regexp, like go test -skip"`
+}
+
+// testFilter selects the tests by their names
+type testFilter struct {
+	run  *regexp.Regexp // nil: every test
+	skip *regexp.Regexp // nil: no test
+}
+
+// testFilter compiles the filter flags. It returns nil if no test is filtered.
+func (f *FilterFlags) testFilter() (_ *testFilter, err error) {
+	if f.RunTests == "" && f.SkipTests == "" {
+		return nil, nil
+	}
+	filter := &testFilter{}
+	if filter.run, err = compileTopLevel(f.RunTests); err != nil {
+		return nil, fmt.Errorf("--run: %w", err)
+	}
+	if filter.skip, err = compileTopLevel(f.SkipTests); err != nil {
+		return nil, fmt.Errorf("--skip-tests: %w", err)
+	}
+	return filter, nil
+}
+
+// compileTopLevel compiles a pattern matching top-level tests. Patterns for
+// subtests (with slashes separating the levels, as go test splits them) are
+// rejected, since the subtests run by a test are not known before it runs.
+func compileTopLevel(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+	for _, alt := range splitAlternatives(pattern) {
+		if levels := splitLevels(alt); len(levels) > 1 {
+			return nil, fmt.Errorf("pattern %q selects subtests, only test functions can be filtered", pattern)
+		}
+	}
+	return regexp.Compile(pattern)
+}
+
+// splitAlternatives splits a pattern by the | outside of brackets and parentheses
+func splitAlternatives(s string) []string {
+	return splitPattern(s, '|')
+}
+
+// splitLevels splits a pattern into the patterns of each level of subtests
+// by the / outside of brackets and parentheses, like go test does
+func splitLevels(s string) []string {
+	return splitPattern(s, '/')
+}
+
+func splitPattern(s string, sep byte) []string {
+	var parts []string
+	brackets, parens := 0, 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '[':
+			brackets++
+		case ']':
+			brackets = max(brackets-1, 0)
+		case '(':
+			if brackets == 0 {
+				parens++
+			}
+		case ')':
+			if brackets == 0 {
+				parens--
+			}
+		case '\\':
+			i++
+		case sep:
+			if brackets == 0 && parens == 0 {
+				parts = append(parts, s[:i])
+				s, i = s[i+1:], -1
+			}
+		}
+	}
+	return append(parts, s)
+}
+
+// matched reports whether a test with the name is run
+func (f *testFilter) matched(name string) bool {
+	return (f.run == nil || f.run.MatchString(name)) && (f.skip == nil || !f.skip.MatchString(name))
+}
+
+// filterTests keeps only the tests matching --run and not matching --skip-tests
+func (c *CLI) filterTests() {
+	if c.filter == nil {
+		return
+	}
+	infos := make([]types.TestInfo, 0, len(c.testInfos))
+	for _, test := range c.testInfos {
+		if c.filter.matched(test.Function) {
+			infos = append(infos, test)
+		}
+	}
+	log.Printf("Selected %d tests by name, %d tests filtered out\n", len(infos), len(c.testInfos)-len(infos))
+	c.testInfos = infos
+}
@@ -0,0 +1,73 @@
+package command
+
+import (
+	"cmp"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/takuo/go-testsplitter/internal/history"
+)
+
+// FlakyCmd reports flaky tests from the results of previous runs
+type FlakyCmd struct {
+	ResultFlags `embed:""`
+
+	Subtests bool `long:"subtests" help:"Include subtests in the report"`
+	Limit    int  `short:"l" long:"limit" default:"0" help:"Maximum number of tests to report (0: unlimited)"`
+}
+
+type flakyTest struct {
+	history.Outcomes
+	Package  string
+	Function string
+}
+
+// Run prints the tests that failed or passed only on a rerun, ordered by flake rate
+func (c *FlakyCmd) Run() error {
+	db, err := c.loadHistory()
+	if err != nil {
+		log.Printf("Warning: Failed to load history: %v", err)
+	}
+
+	var tests []flakyTest
+	for key, samples := range db.Tests {
+		pkg, fn := history.SplitKey(key)
+		if history.IsPackageKey(key) || (!c.Subtests && strings.Contains(fn, "/")) {
+			continue
+		}
+		o := history.SummarizeOutcomes(samples)
+		if o.Flakes == 0 && o.Failures == 0 {
+			continue
+		}
+		tests = append(tests, flakyTest{Outcomes: o, Package: pkg, Function: fn})
+	}
+	slices.SortFunc(tests, func(a, b flakyTest) int {
+		return cmp.Or(
+			cmp.Compare(b.FlakeRate(), a.FlakeRate()),
+			cmp.Compare(b.Failures, a.Failures),
+			b.LastFailure.Compare(a.LastFailure),
+			cmp.Compare(a.Package, b.Package),
+			cmp.Compare(a.Function, b.Function),
+		)
+	})
+	if c.Limit > 0 && len(tests) > c.Limit {
+		tests = tests[:c.Limit]
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TEST\tPACKAGE\tRUNS\tFLAKES\tFLAKE RATE\tFAILURES\tLAST FAILURE")
+	for _, t := range tests {
+		last := "-"
+		if !t.LastFailure.IsZero() {
+			last = t.LastFailure.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%.1f%%\t%d\t%s\n",
+			t.Function, t.Package, t.Runs, t.Flakes, t.FlakeRate()*100, t.Failures, last)
+	}
+	return w.Flush()
+}
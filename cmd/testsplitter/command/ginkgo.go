@@ -0,0 +1,69 @@
+package command
+
+import (
+	"log"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/takuo/go-testsplitter/internal/ginkgo"
+	"github.com/takuo/go-testsplitter/internal/scanner"
+	"github.com/takuo/go-testsplitter/internal/types"
+)
+
+// splitGinkgoSuites replaces every Ginkgo suite by parts, each running a
+// balanced group of its specs. The specs are listed with a dry run of the test
+// binary and their durations come from Ginkgo JSON reports in the history.
+// The parts take at most SubtestThreshold, or the suite is split by the number
+// of nodes when no threshold is set.
+func (c *CLI) splitGinkgoSuites() {
+	if !c.Ginkgo {
+		return
+	}
+	binDir, err := filepath.Abs(c.BinariesDir)
+	if err != nil {
+		log.Printf("Failed to get absolute binary path: %v\n", err)
+		return
+	}
+
+	infos := make([]types.TestInfo, 0, len(c.testInfos))
+	for _, test := range c.testInfos {
+		if test.IsPartial() || !c.testFunc(test.Package, test.Function).Ginkgo {
+			infos = append(infos, test)
+			continue
+		}
+		binary := filepath.Join(binDir, binaryName(test.Package))
+		specs, err := ginkgo.ListSpecs(binary, c.packages.dir(test.Package), test.Function)
+		if err != nil || len(specs) < 2 {
+			if err != nil {
+				log.Printf("Failed to list Ginkgo specs of %s: %v\n", test.Key(), err)
+			}
+			infos = append(infos, test)
+			continue
+		}
+
+		known := make(map[string]time.Duration)
+		for _, spec := range specs {
+			if d, ok := c.testDurations[ginkgo.SpecKey(test.Package, spec)]; ok {
+				known[spec] = d
+			}
+		}
+		threshold := c.SubtestThreshold
+		if threshold <= 0 {
+			threshold = max(test.Duration/time.Duration(max(c.Nodes, 1)), 1)
+		}
+		parts := splitBySubtests(test, subtestDurations(test.Duration, known, specs), threshold)
+		for i := range parts {
+			parts[i].Ginkgo = len(parts) > 1
+		}
+		log.Printf("Split Ginkgo suite %s into %d parts by %d specs (%d with history)\n", test.Key(), len(parts), len(specs), len(known))
+		infos = append(infos, parts...)
+	}
+	c.testInfos = infos
+}
+
+// hasGinkgoSuite reports whether the package has a Ginkgo suite, so its test
+// binary accepts the Ginkgo flags
+func (c *CLI) hasGinkgoSuite(pkg string) bool {
+	return slices.ContainsFunc(c.tests[pkg], func(tf scanner.TestFunc) bool { return tf.Ginkgo })
+}
@@ -0,0 +1,124 @@
+package command
+
+import (
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/takuo/go-testsplitter/internal/history"
+	"github.com/takuo/go-testsplitter/internal/parser"
+)
+
+// StoreFlags locate the timing database
+type StoreFlags struct {
+	TimingsDB string `long:"timings-db" default:"./test-timings.db" help:"Path to the timing database"`
+}
+
+// ResultFlags locate the results of previous test runs
+type ResultFlags struct {
+	StoreFlags `embed:""`
+
+	JSONDir string `short:"j" long:"json-dir" default:"./test-json" help:"Directory containing go test -json results and JUnit XML reports"`
+}
+
+// HistoryFlags locate and reduce the results of previous test runs
+type HistoryFlags struct {
+	ResultFlags `embed:""`
+
+	Estimator string        `short:"e" long:"estimator" enum:"median,p90,max,ewma" default:"median" help:"How to reduce the durations of a test observed in multiple runs (median, p90, max, ewma)"`
+	HalfLife  time.Duration `long:"half-life" default:"168h" help:"Half-life of the sample weight for the ewma estimator"`
+}
+
+// loadHistory reads the timing database first and merges the raw result files in JSONDir into its history.
+// If the database cannot be read, the raw result files are still merged and the error is returned along them.
+func (h *ResultFlags) loadHistory() (*history.DB, error) {
+	db, dbErr := history.Open(h.TimingsDB)
+	if dbErr != nil {
+		db = &history.DB{Tests: make(history.History), Fingerprints: make(map[string]string)}
+	}
+	if len(db.Tests) > 0 {
+		log.Printf("Loaded %d testcases from timing database %s\n", len(db.Tests), h.TimingsDB)
+	}
+
+	data, files, err := loadHistoryDir(h.JSONDir)
+	db.Tests.Merge(data)
+	log.Printf("Loaded %d testcases durations from %d files in %s\n", len(data), files, h.JSONDir)
+	return db, errors.Join(dbErr, err)
+}
+
+// runReport matches the result files of a run of a node written by the built-in
+// template, test-N-M.jsonl and junit-N-M.xml, capturing the format and the run
+var runReport = regexp.MustCompile(`^(test|junit)-(\d+-\d+)\.(?:jsonl|xml)(?:\.gz)?$`)
+
+// runOf returns the format of a result file written by the built-in template
+// and the run it belongs to: the node and line in the artifact directory
+// holding the results of a CI run. JUnit reports are in its test-reports
+// subdirectory. ok is false for other files.
+func runOf(path string) (format, run string, ok bool) {
+	m := runReport.FindStringSubmatch(filepath.Base(path))
+	if m == nil {
+		return "", "", false
+	}
+	dir := filepath.Dir(path)
+	if m[1] == "junit" && filepath.Base(dir) == "test-reports" {
+		dir = filepath.Dir(dir)
+	}
+	return m[1], filepath.Join(dir, m[2]), true
+}
+
+// loadHistoryDir recursively reads all result files in dir. A JUnit report of
+// a run that also has a go test -json result in the same artifact directory is
+// skipped, as it records the same tests with less detail. The JSONL results
+// are either in the artifact directory or in a subdirectory of it, like -j.
+func loadHistoryDir(dir string) (hist history.History, files int, err error) {
+	var paths []string
+	jsonRuns := make(map[string]bool)
+	err = filepath.WalkDir(dir, func(path string, _ fs.DirEntry, err error) error {
+		if err != nil {
+			return nil // Skip files that can't be accessed
+		}
+		if !parser.Supported(path) {
+			return nil
+		}
+		if format, run, ok := runOf(path); ok && format == "test" {
+			jsonRuns[run] = true
+			jsonRuns[filepath.Join(filepath.Dir(filepath.Dir(run)), filepath.Base(run))] = true
+		}
+		paths = append(paths, path)
+		return nil
+	})
+
+	hist = make(history.History)
+	for _, path := range paths {
+		if format, run, ok := runOf(path); ok && format == "junit" && jsonRuns[run] {
+			log.Printf("Skipping %s, the run is read from its go test -json result\n", path)
+			continue
+		}
+		data, err := parseHistoryFile(path)
+		if err != nil {
+			log.Printf("Failed to read %s: %v\n", path, err)
+			if len(data) == 0 {
+				continue // Skip files that can't be read
+			}
+		}
+		files++
+		hist.Merge(data)
+	}
+	return hist, files, err
+}
+
+// parseHistoryFile parses a go test -json (JSONL) or JUnit XML result file,
+// possibly compressed or archived
+func parseHistoryFile(path string) (history.History, error) {
+	fp, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fp.Close()
+
+	return parser.ParseFile(filepath.Base(path), fp)
+}
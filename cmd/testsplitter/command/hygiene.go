@@ -0,0 +1,173 @@
+package command
+
+import (
+	"fmt"
+	"go/build"
+	"log"
+	"maps"
+	"os"
+	"slices"
+	"text/tabwriter"
+
+	"github.com/takuo/go-testsplitter/internal/history"
+	"github.com/takuo/go-testsplitter/internal/hygiene"
+	"github.com/takuo/go-testsplitter/internal/scanner"
+)
+
+// TimingsHygieneCmd reports tests in the timing database that no longer
+// exist and tests without history, and optionally cleans the database up
+type TimingsHygieneCmd struct {
+	StoreFlags   `embed:""`
+	PackageFlags `embed:""`
+	BuildFlags   `embed:""`
+	ScanFlags    `embed:""`
+
+	Apply bool `long:"apply" help:"Carry the history of renamed tests over, remove orphaned tests and record the fingerprints of the current tests"`
+}
+
+// Run checks the timing database against the tests in the packages
+func (c *TimingsHygieneCmd) Run() error {
+	list, err := c.listPackages()
+	if err != nil {
+		return err
+	}
+	packages := newPackageList(list)
+	tests, err := packages.scan(c.scanContext(), &c.ScanFlags)
+	if err != nil {
+		return fmt.Errorf("failed to parse test functions: %w", err)
+	}
+	db, err := history.Open(c.TimingsDB)
+	if err != nil {
+		return err
+	}
+	db.Tests.RenamePackages(packages.recordedAliases(db.Tests))
+	renameFingerprintPackages(db.Fingerprints, packages.aliases)
+
+	current := fingerprints(packages.paths, tests)
+	// packages that cannot be found any more are checked too, all their tests are orphaned
+	var unknown []string
+	for key := range db.Tests {
+		pkg, _ := history.SplitKey(key)
+		if _, ok := current[pkg]; !ok && !slices.Contains(unknown, pkg) {
+			unknown = append(unknown, pkg)
+		}
+	}
+	for _, pkg := range gonePackages(unknown) {
+		current[pkg] = map[string]string{}
+	}
+	report := hygiene.Check(db.Tests, db.Fingerprints, current)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STATUS\tTEST\tCURRENT")
+	for _, key := range report.Orphaned {
+		fmt.Fprintf(w, "orphaned\t%s\t-\n", key)
+	}
+	for _, from := range slices.Sorted(maps.Keys(report.Renamed)) {
+		fmt.Fprintf(w, "renamed\t%s\t%s\n", from, report.Renamed[from])
+	}
+	for _, key := range report.Missing {
+		fmt.Fprintf(w, "no-history\t-\t%s\n", key)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if !c.Apply {
+		return nil
+	}
+
+	removed := report.Apply(db.Tests)
+	for _, key := range report.Orphaned {
+		delete(db.Fingerprints, key)
+	}
+	for from := range report.Renamed {
+		delete(db.Fingerprints, from)
+	}
+	for pkg, fns := range current {
+		for fn, fp := range fns {
+			db.Fingerprints[history.Key(pkg, fn)] = fp
+		}
+	}
+	if err := history.Save(c.TimingsDB, db); err != nil {
+		return err
+	}
+	log.Printf("Carried over %d renamed tests, removed %d testcases from %s\n", len(report.Renamed), removed, c.TimingsDB)
+	return nil
+}
+
+// scanContext returns the build context scanning every test file. Tests in
+// files excluded by the build constraints, e.g. for another GOOS or without
+// their tags, still exist and must not be reported as orphaned.
+func (c *TimingsHygieneCmd) scanContext() *build.Context {
+	ctx := c.buildContext()
+	ctx.UseAllFiles = true
+	return ctx
+}
+
+// gonePackages returns the packages that are neither found as import paths nor as directories
+func gonePackages(packages []string) []string {
+	if len(packages) == 0 {
+		return nil
+	}
+	found := make(map[string]bool)
+	resolved, err := scanner.ResolvePackages(packages)
+	if err != nil {
+		log.Printf("Warning: Failed to resolve packages, checking them as directories: %v", err)
+	}
+	for _, pkg := range resolved {
+		found[pkg.ImportPath] = true
+		for _, alias := range pkg.Aliases() {
+			found[alias] = true
+		}
+	}
+	var gone []string
+	for _, pkg := range packages {
+		if found[pkg] {
+			continue
+		}
+		if err != nil {
+			// without go list, only missing directories are known to be gone
+			if _, statErr := os.Stat(pkg); !os.IsNotExist(statErr) {
+				continue
+			}
+		}
+		gone = append(gone, pkg)
+	}
+	return gone
+}
+
+// renameFingerprintPackages moves the fingerprints recorded under other names
+// of packages to the canonical names given by aliases, like History.RenamePackages
+func renameFingerprintPackages(fingerprints map[string]string, aliases map[string]string) {
+	for _, key := range slices.Collect(maps.Keys(fingerprints)) {
+		pkg, fn := history.SplitKey(key)
+		if to, ok := aliases[pkg]; ok && to != pkg {
+			fingerprints[history.Key(to, fn)] = fingerprints[key]
+			delete(fingerprints, key)
+		}
+	}
+}
+
+// fingerprints returns the fingerprints of the test functions of every package
+func fingerprints(packages []string, tests map[string][]scanner.TestFunc) map[string]map[string]string {
+	current := make(map[string]map[string]string, len(packages))
+	for _, pkg := range packages {
+		current[pkg] = make(map[string]string, len(tests[pkg]))
+		for _, tf := range tests[pkg] {
+			current[pkg][tf.Name] = tf.Fingerprint
+		}
+	}
+	return current
+}
+
+// carryOverRenames moves the history of tests renamed since the fingerprints
+// were recorded to their current names
+func (c *CLI) carryOverRenames(recorded map[string]string) {
+	if len(recorded) == 0 {
+		return
+	}
+	report := hygiene.Check(c.history, recorded, fingerprints(c.packages.paths, c.tests))
+	for from, to := range report.Renamed {
+		c.history.Rename(from, to)
+		log.Printf("Carried history of %s over to renamed %s\n", from, to)
+	}
+}
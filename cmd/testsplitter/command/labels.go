@@ -0,0 +1,117 @@
+package command
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/takuo/go-testsplitter/internal/labels"
+	"github.com/takuo/go-testsplitter/internal/types"
+)
+
+// LabelFlags select the tests to run by their labels. Every test is labeled
+// with its kind (test, fuzz, example, suite or ginkgo), the build tags its file
+// requires, its //testsplitter:label directives and the matching label rules.
+type LabelFlags struct {
+	Select       string   `long:"select" help:"Run only the tests whose labels match the expression, e.g. 'e2e or smoke'"`
+	Skip         string   `long:"skip" help:"Skip the tests whose labels match the expression, e.g. 'slow and not smoke'"`
+	LabelTest    []string `long:"label-test" placeholder:"LABEL=GLOB" help:"Label the tests whose name matches the glob, e.g. slow=*Slow*"`
+	LabelPackage []string `long:"label-package" placeholder:"LABEL=GLOB" help:"Label the tests in the packages whose import path or directory matches the glob, e.g. e2e=test/e2e/..."`
+}
+
+// labelSelector selects the tests by the label expressions
+type labelSelector struct {
+	selectExpr labels.Expr // nil: every test
+	skipExpr   labels.Expr // nil: no test
+	testRules  []labels.Rule
+	pkgRules   []labels.Rule
+}
+
+// labelSelector parses the label flags. It returns nil if no test is filtered.
+func (l *LabelFlags) labelSelector() (_ *labelSelector, err error) {
+	if l.Select == "" && l.Skip == "" {
+		return nil, nil
+	}
+	s := &labelSelector{}
+	if l.Select != "" {
+		if s.selectExpr, err = labels.Parse(l.Select); err != nil {
+			return nil, fmt.Errorf("--select: %w", err)
+		}
+	}
+	if l.Skip != "" {
+		if s.skipExpr, err = labels.Parse(l.Skip); err != nil {
+			return nil, fmt.Errorf("--skip: %w", err)
+		}
+	}
+	for _, rule := range l.LabelTest {
+		r, err := labels.ParseRule(rule)
+		if err != nil {
+			return nil, fmt.Errorf("--label-test: %w", err)
+		}
+		s.testRules = append(s.testRules, r)
+	}
+	for _, rule := range l.LabelPackage {
+		r, err := labels.ParseRule(rule)
+		if err != nil {
+			return nil, fmt.Errorf("--label-package: %w", err)
+		}
+		s.pkgRules = append(s.pkgRules, r)
+	}
+	return s, nil
+}
+
+// selected reports whether a test with the labels is selected
+func (s *labelSelector) selected(labels []string) bool {
+	return (s.selectExpr == nil || s.selectExpr.Match(labels)) && (s.skipExpr == nil || !s.skipExpr.Match(labels))
+}
+
+// testLabels returns the labels of the test
+func (c *CLI) testLabels(pkg, fn string) []string {
+	tf := c.testFunc(pkg, fn)
+	var labels []string
+	switch {
+	case strings.HasPrefix(fn, "Fuzz"):
+		labels = append(labels, "fuzz")
+	case strings.HasPrefix(fn, "Example"):
+		labels = append(labels, "example")
+	case tf.Ginkgo:
+		labels = append(labels, "test", "ginkgo")
+	case len(tf.SuiteMethods) > 0:
+		labels = append(labels, "test", "suite")
+	default:
+		labels = append(labels, "test")
+	}
+	labels = append(labels, tf.Tags...)
+	labels = append(labels, tf.Directives.Labels...)
+	if c.selector != nil {
+		for _, rule := range c.selector.testRules {
+			if rule.Match(fn) {
+				labels = append(labels, rule.Label)
+			}
+		}
+		for _, rule := range c.selector.pkgRules {
+			if rule.Match(pkg) || rule.Match(filepath.ToSlash(c.packages.dir(pkg))) {
+				labels = append(labels, rule.Label)
+			}
+		}
+	}
+	slices.Sort(labels)
+	return slices.Compact(labels)
+}
+
+// selectTests keeps only the tests selected by --select and --skip
+func (c *CLI) selectTests() {
+	if c.selector == nil {
+		return
+	}
+	infos := make([]types.TestInfo, 0, len(c.testInfos))
+	for _, test := range c.testInfos {
+		if c.selector.selected(c.testLabels(test.Package, test.Function)) {
+			infos = append(infos, test)
+		}
+	}
+	log.Printf("Selected %d tests by labels, %d tests not selected\n", len(infos), len(c.testInfos)-len(infos))
+	c.testInfos = infos
+}
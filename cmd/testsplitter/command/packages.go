@@ -0,0 +1,253 @@
+package command
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"go/build"
+	"log"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"github.com/takuo/go-testsplitter/internal/history"
+	"github.com/takuo/go-testsplitter/internal/scanner"
+)
+
+// PackageFlags select the packages to test. Scanning covers every module of
+// the go.work workspace or nested under the current directory.
+type PackageFlags struct {
+	ScanPackages    bool     `short:"s" long:"scan-packages" help:"Scan Go packages from the current directory (like 'go list'). If not specified, package list is read from stdin."`
+	Exclude         string   `short:"x" long:"exclude" help:"Regex pattern to exclude packages by import path"`
+	IncludePackages string   `long:"include-packages" placeholder:"REGEXP" help:"Regex pattern to include only the packages whose import path matches"`
+	PackagePattern  []string `long:"package-pattern" sep:"," placeholder:"PATTERN" help:"Package patterns to scan with --scan-packages, e.g. ./services/... (default: ./...)"`
+}
+
+// BuildFlags select the build configuration of the test binaries.
+// The same configuration decides which test files are scanned.
+type BuildFlags struct {
+	Tags   []string `long:"tags" sep:"," help:"Comma-separated build tags for building test binaries and scanning test files"`
+	GOOS   string   `long:"goos" env:"GOOS" help:"Target operating system (default: the host)"`
+	GOARCH string   `long:"goarch" env:"GOARCH" help:"Target architecture (default: the host)"`
+}
+
+// ScanFlags configure the scanning of the test files
+type ScanFlags struct {
+	ScanConcurrency int    `long:"scan-concurrency" default:"0" help:"Number of packages whose test files are scanned at once (0: GOMAXPROCS)"`
+	ScanCache       string `long:"scan-cache" placeholder:"PATH" help:"Path to the cache of scanned test files, reused while their content is unchanged, or 'off' (default: in the user cache directory)"`
+}
+
+// scanCache loads the cache of scanned test files and returns it with the
+// path to save it to, or nil if disabled
+func (f *ScanFlags) scanCache() (cache *scanner.Cache, path string) {
+	path = f.ScanCache
+	if path == "off" {
+		return nil, ""
+	}
+	if path == "" {
+		dir, err := os.UserCacheDir()
+		if err != nil {
+			log.Printf("Warning: No user cache directory, scanning without cache: %v", err)
+			return nil, ""
+		}
+		// each working tree has its own cache, so switching trees doesn't evict entries
+		cwd, _ := os.Getwd()
+		sum := sha256.Sum256([]byte(cwd))
+		path = filepath.Join(dir, "go-testsplitter", "scan-"+hex.EncodeToString(sum[:8])+".json.gz")
+	}
+	cache, err := scanner.LoadCache(path)
+	if err != nil {
+		log.Printf("Warning: Failed to load scan cache, rebuilding it: %v", err)
+		cache = scanner.NewCache()
+	}
+	return cache, path
+}
+
+// buildContext returns the go/build context evaluating the build constraints of the test files
+func (b *BuildFlags) buildContext() *build.Context {
+	ctx := build.Default
+	if b.GOOS != "" {
+		ctx.GOOS = b.GOOS
+	}
+	if b.GOARCH != "" {
+		ctx.GOARCH = b.GOARCH
+	}
+	// like the go command, cgo is disabled when cross-compiling unless enabled explicitly
+	if _, ok := os.LookupEnv("CGO_ENABLED"); !ok && (ctx.GOOS != runtime.GOOS || ctx.GOARCH != runtime.GOARCH) {
+		ctx.CgoEnabled = false
+	}
+	ctx.BuildTags = b.Tags
+	return &ctx
+}
+
+// buildArgs returns the arguments and the environment for `go test -c`
+func (b *BuildFlags) buildArgs() (args, env []string) {
+	if len(b.Tags) > 0 {
+		args = append(args, "-tags", strings.Join(b.Tags, ","))
+	}
+	if b.GOOS != "" {
+		env = append(env, "GOOS="+b.GOOS)
+	}
+	if b.GOARCH != "" {
+		env = append(env, "GOARCH="+b.GOARCH)
+	}
+	return args, env
+}
+
+// listPackages returns the packages either scanned from the current directory
+// or read from stdin, as directories or import paths, filtered by
+// --include-packages and --exclude
+func (p *PackageFlags) listPackages() (packages []scanner.Package, err error) {
+	include, err := compilePackagePattern("--include-packages", p.IncludePackages)
+	if err != nil {
+		return nil, err
+	}
+	exclude, err := compilePackagePattern("--exclude", p.Exclude)
+	if err != nil {
+		return nil, err
+	}
+	if p.ScanPackages {
+		if packages, err = scanner.ScanPackages(p.PackagePattern...); err != nil {
+			return nil, fmt.Errorf("failed to scan packages: %v", err)
+		}
+	} else {
+		names, err := readPackagesFromStdin()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read packages from stdin: %v", err)
+		}
+		log.Printf("Read %d packages from stdin: %v", len(names), names)
+		if packages, err = scanner.ResolvePackages(names); err != nil {
+			log.Printf("Warning: Failed to resolve packages, using them as directories: %v", err)
+			packages = make([]scanner.Package, len(names))
+			for i, name := range names {
+				packages[i] = scanner.Package{ImportPath: name, Dir: name}
+			}
+		}
+	}
+	if include == nil && exclude == nil {
+		return packages, nil
+	}
+	filtered := packages[:0]
+	for _, pkg := range packages {
+		if (include == nil || include.MatchString(pkg.ImportPath)) && (exclude == nil || !exclude.MatchString(pkg.ImportPath)) {
+			filtered = append(filtered, pkg)
+		}
+	}
+	log.Printf("Selected %d packages by import path, %d packages filtered out", len(filtered), len(packages)-len(filtered))
+	return filtered, nil
+}
+
+// compilePackagePattern compiles the regexp of a package filter flag, nil if not given
+func compilePackagePattern(flag, pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s pattern: %v", flag, err)
+	}
+	return re, nil
+}
+
+// packageList is the list of packages to test, identified by their import paths
+type packageList struct {
+	paths   []string
+	dirs    map[string]string // import path -> directory
+	aliases map[string]string // directory or path in the module -> import path
+}
+
+func newPackageList(packages []scanner.Package) packageList {
+	l := packageList{
+		dirs:    make(map[string]string, len(packages)),
+		aliases: make(map[string]string, len(packages)),
+	}
+	for _, pkg := range packages {
+		l.paths = append(l.paths, pkg.ImportPath)
+		l.dirs[pkg.ImportPath] = pkg.Dir
+		for _, alias := range pkg.Aliases() {
+			l.aliases[alias] = pkg.ImportPath
+		}
+	}
+	return l
+}
+
+// recordedAliases returns the aliases extended with the packages of the history
+// recorded by the absolute directory of another checkout, like the suite paths
+// of Ginkgo reports. Such a directory is matched by the longest alias it ends with.
+func (l *packageList) recordedAliases(h history.History) map[string]string {
+	aliases := maps.Clone(l.aliases)
+	for key := range h {
+		pkg, _ := history.SplitKey(key)
+		if _, ok := aliases[pkg]; ok || !path.IsAbs(pkg) {
+			continue
+		}
+		var match string
+		for alias := range l.aliases {
+			if len(alias) > len(match) && strings.HasSuffix(pkg, "/"+alias) {
+				match = alias
+			}
+		}
+		if match != "" {
+			aliases[pkg] = l.aliases[match]
+		}
+	}
+	return aliases
+}
+
+// dir returns the directory of the package, or the package itself if unknown
+func (l *packageList) dir(pkg string) string {
+	if dir, ok := l.dirs[pkg]; ok {
+		return dir
+	}
+	return pkg
+}
+
+// scan scans the test functions of the packages, keyed by import path
+func (l *packageList) scan(ctx *build.Context, flags *ScanFlags) (map[string][]scanner.TestFunc, error) {
+	dirs := make([]string, len(l.paths))
+	for i, pkg := range l.paths {
+		dirs[i] = l.dir(pkg)
+	}
+	cache, cachePath := flags.scanCache()
+	byDir, err := scanner.ScanTests(ctx, dirs, scanner.WithConcurrency(flags.ScanConcurrency), scanner.WithCache(cache))
+	if err != nil {
+		return nil, err
+	}
+	if cache != nil {
+		hits, misses := cache.Stats()
+		log.Printf("Scanned %d test files, %d unchanged files taken from the cache", hits+misses, hits)
+		if err := cache.Save(cachePath); err != nil {
+			log.Printf("Warning: Failed to save scan cache: %v", err)
+		}
+	}
+	tests := make(map[string][]scanner.TestFunc, len(byDir))
+	for _, pkg := range l.paths {
+		if fns, ok := byDir[l.dir(pkg)]; ok {
+			tests[pkg] = fns
+		}
+	}
+	return tests, nil
+}
+
+// binaryName returns the file name of the test binary of the package,
+// e.g. api.service.foo.test for api/service/foo
+func binaryName(pkg string) string {
+	return strings.ReplaceAll(pkg, "/", ".") + ".test"
+}
+
+func readPackagesFromStdin() (packages []string, err error) {
+	packages = []string{} // initialize packages slice
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		pkg := strings.TrimSpace(scanner.Text())
+		if pkg != "" {
+			packages = append(packages, pkg)
+		}
+	}
+	return packages, scanner.Err()
+}
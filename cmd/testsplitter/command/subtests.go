@@ -0,0 +1,153 @@
+package command
+
+import (
+	"log"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/takuo/go-testsplitter/internal/history"
+	"github.com/takuo/go-testsplitter/internal/testpattern"
+	"github.com/takuo/go-testsplitter/internal/types"
+	"github.com/takuo/go-testsplitter/pkg/durchunk"
+)
+
+// firstLevelSubtests returns the durations of the first-level subtests keyed by the parent test key
+func (c *CLI) firstLevelSubtests() map[string]map[string]time.Duration {
+	subtests := make(map[string]map[string]time.Duration)
+	for key, d := range c.testDurations {
+		pkg, name := history.SplitKey(key)
+		parent, sub, ok := strings.Cut(name, "/")
+		if !ok || strings.Contains(sub, "/") {
+			continue
+		}
+		parentKey := history.Key(pkg, parent)
+		if subtests[parentKey] == nil {
+			subtests[parentKey] = make(map[string]time.Duration)
+		}
+		subtests[parentKey][sub] = d
+	}
+	return subtests
+}
+
+// splitSubtests replaces every test taking longer than SubtestThreshold by parts,
+// each running a balanced group of its first-level subtests in its own invocation.
+func (c *CLI) splitSubtests() {
+	if c.SubtestThreshold <= 0 {
+		return
+	}
+	subtests := c.firstLevelSubtests()
+
+	infos := make([]types.TestInfo, 0, len(c.testInfos))
+	for _, test := range c.testInfos {
+		subs := subtests[history.Key(test.Package, test.Function)]
+		if methods := c.testFunc(test.Package, test.Function).SuiteMethods; len(methods) > 0 {
+			subs = subtestDurations(test.Duration, subs, methods)
+		}
+		if test.Duration <= c.SubtestThreshold || len(subs) < 2 {
+			infos = append(infos, test)
+			continue
+		}
+		parts := splitBySubtests(test, subs, c.SubtestThreshold)
+		log.Printf("Split %s into %d parts by %d subtests\n", test.Key(), len(parts), len(subs))
+		infos = append(infos, parts...)
+	}
+	c.testInfos = infos
+}
+
+// subtestDurations returns the durations of the subtests of a test found in
+// the source, e.g. the methods of a testify suite. Subtests without history are
+// estimated as the mean of the others, or share the test duration evenly.
+func subtestDurations(total time.Duration, known map[string]time.Duration, names []string) map[string]time.Duration {
+	subs := make(map[string]time.Duration, len(names))
+	var sum time.Duration
+	for _, name := range names {
+		if d, ok := known[name]; ok {
+			subs[name] = d
+			sum += d
+		}
+	}
+	estimate := total / time.Duration(len(names))
+	if len(subs) > 0 {
+		estimate = sum / time.Duration(len(subs))
+	}
+	for _, name := range names {
+		if _, ok := subs[name]; !ok {
+			subs[name] = estimate
+		}
+	}
+	return subs
+}
+
+// splitBySubtests splits a test into parts of at most threshold if possible.
+// All parts but the last run the listed subtests; the last part runs every other
+// subtest, so subtests without history (and the parent itself) still run once.
+func splitBySubtests(test types.TestInfo, subs map[string]time.Duration, threshold time.Duration) []types.TestInfo {
+	var sum time.Duration
+	for _, d := range subs {
+		sum += d
+	}
+	// time spent in the parent outside of the subtests is paid by every part
+	own := max(test.Duration-sum, 0)
+
+	n := min(int((test.Duration+threshold-1)/threshold), len(subs))
+	if n < 2 {
+		// e.g. a test taking no time
+		return []types.TestInfo{test}
+	}
+	var groups [][]string
+	for _, chunk := range durchunk.SplitBalanced(maps.All(subs), n) {
+		if len(chunk.Keys) > 0 {
+			groups = append(groups, slices.Sorted(slices.Values(chunk.Keys)))
+		}
+	}
+	if len(groups) < 2 {
+		return []types.TestInfo{test}
+	}
+
+	parts := make([]types.TestInfo, len(groups))
+	var others []string
+	for i, group := range groups {
+		// parts keep the scheduling hints of the test
+		part := test
+		part.Duration, part.Part = own, i
+		for _, name := range group {
+			part.Duration += subs[name]
+		}
+		if i < len(groups)-1 {
+			part.Subtests = group
+			others = append(others, group...)
+		} else {
+			part.SkipSubtests = others
+		}
+		parts[i] = part
+	}
+	return parts
+}
+
+// partialTestLine returns the script line running a single test, or a part of
+// it when split by subtests
+func partialTestLine(test *types.TestInfo, flags string) types.TestLine {
+	tl := types.TestLine{
+		ImportPath:  test.Package,
+		TestPattern: testpattern.Run([]string{test.Function}),
+		Flags:       flags,
+	}
+	if test.Ginkgo {
+		if len(test.Subtests) > 0 {
+			tl.Args = "-ginkgo.focus '" + testpattern.Exact(test.Subtests) + "'"
+		}
+		if len(test.SkipSubtests) > 0 {
+			tl.Args = "-ginkgo.skip '" + testpattern.Exact(test.SkipSubtests) + "'"
+		}
+		return tl
+	}
+	if len(test.Subtests) > 0 {
+		tl.TestPattern = testpattern.Subtests(test.Function, test.Subtests)
+	}
+	if len(test.SkipSubtests) > 0 {
+		tl.SkipPattern = testpattern.Subtests(test.Function, test.SkipSubtests)
+	}
+	return tl
+}
@@ -0,0 +1,247 @@
+package command
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/takuo/go-testsplitter/internal/history"
+	"github.com/takuo/go-testsplitter/internal/parser"
+)
+
+// TimingsCmd groups the subcommands managing the timing database
+type TimingsCmd struct {
+	Ingest  TimingsIngestCmd  `cmd:"" help:"Add go test -json streams or result files to the timing database"`
+	Show    TimingsShowCmd    `cmd:"" help:"Show per-test statistics from the timing database"`
+	Export  TimingsExportCmd  `cmd:"" help:"Export per-test statistics as CSV or JSON"`
+	Prune   TimingsPruneCmd   `cmd:"" help:"Remove old or excess samples from the timing database"`
+	Hygiene TimingsHygieneCmd `cmd:"" help:"Find orphaned, renamed and new tests by comparing the timing database with the source"`
+}
+
+// TimingsIngestCmd adds results to the timing database
+type TimingsIngestCmd struct {
+	StoreFlags `embed:""`
+
+	Paths []string `arg:"" optional:"" help:"Result files or directories to ingest (go test -json or JUnit XML). Reads go test -json from stdin if omitted or '-'."`
+}
+
+// Run ingests the results
+func (c *TimingsIngestCmd) Run() error {
+	db, err := history.Open(c.TimingsDB)
+	if err != nil {
+		return err
+	}
+	hist := db.Tests
+	before := len(hist)
+
+	paths := c.Paths
+	if len(paths) == 0 {
+		paths = []string{"-"}
+	}
+	for _, path := range paths {
+		data, err := readHistoryPath(path)
+		if err != nil {
+			return fmt.Errorf("failed to ingest %s: %w", path, err)
+		}
+		hist.Merge(data)
+	}
+
+	if err := history.Save(c.TimingsDB, db); err != nil {
+		return err
+	}
+	log.Printf("Ingested into %s: %d testcases (%d new)\n", c.TimingsDB, len(hist), len(hist)-before)
+	return nil
+}
+
+// readHistoryPath reads a result file, all result files in a directory or stdin ("-")
+func readHistoryPath(path string) (history.History, error) {
+	if path == "-" {
+		return parser.ParseGoTestJSONL(bufio.NewScanner(os.Stdin)), nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		data, _, err := loadHistoryDir(path)
+		return data, err
+	}
+	return parseHistoryFile(path)
+}
+
+// TimingsShowCmd prints per-test statistics
+type TimingsShowCmd struct {
+	StoreFlags `embed:""`
+
+	Filter string `arg:"" optional:"" help:"Regex pattern to filter tests by 'package:Function'"`
+}
+
+// Run shows the statistics
+func (c *TimingsShowCmd) Run() error {
+	stats, err := loadStats(c.TimingsDB, c.Filter)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TEST\tSAMPLES\tMEDIAN\tP90\tMAX\tLAST")
+	for _, st := range stats {
+		last := "-"
+		if !st.Last.IsZero() {
+			last = st.Last.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s\n", st.Key, st.Samples,
+			st.Median.Round(time.Millisecond), st.P90.Round(time.Millisecond), st.Max.Round(time.Millisecond), last)
+	}
+	return w.Flush()
+}
+
+// TimingsExportCmd exports per-test statistics
+type TimingsExportCmd struct {
+	StoreFlags `embed:""`
+
+	Format string `short:"f" long:"format" enum:"csv,json" default:"csv" help:"Output format (csv, json)"`
+	Output string `short:"o" long:"output" default:"-" help:"Output file ('-' for stdout)"`
+	Filter string `arg:"" optional:"" help:"Regex pattern to filter tests by 'package:Function'"`
+}
+
+// exportRecord is a row of the exported statistics, durations are in seconds
+type exportRecord struct {
+	Package  string  `json:"package"`
+	Function string  `json:"function"`
+	Samples  int     `json:"samples"`
+	Min      float64 `json:"min"`
+	Median   float64 `json:"median"`
+	P90      float64 `json:"p90"`
+	Max      float64 `json:"max"`
+	Mean     float64 `json:"mean"`
+	Last     string  `json:"last,omitempty"`
+}
+
+// Run exports the statistics
+func (c *TimingsExportCmd) Run() (err error) {
+	stats, err := loadStats(c.TimingsDB, c.Filter)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if c.Output != "-" {
+		fp, err := os.Create(c.Output)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", c.Output, err)
+		}
+		defer func() { err = errors.Join(err, fp.Close()) }()
+		w = fp
+	}
+
+	records := make([]exportRecord, 0, len(stats))
+	for _, st := range stats {
+		pkg, fn := history.SplitKey(st.Key)
+		rec := exportRecord{
+			Package:  pkg,
+			Function: fn,
+			Samples:  st.Samples,
+			Min:      st.Min.Seconds(),
+			Median:   st.Median.Seconds(),
+			P90:      st.P90.Seconds(),
+			Max:      st.Max.Seconds(),
+			Mean:     st.Mean.Seconds(),
+		}
+		if !st.Last.IsZero() {
+			rec.Last = st.Last.Format(time.RFC3339Nano)
+		}
+		records = append(records, rec)
+	}
+
+	if c.Format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(records)
+	}
+
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"package", "function", "samples", "min", "median", "p90", "max", "mean", "last"})
+	for _, rec := range records {
+		cw.Write([]string{
+			rec.Package, rec.Function, strconv.Itoa(rec.Samples),
+			formatFloat(rec.Min), formatFloat(rec.Median), formatFloat(rec.P90), formatFloat(rec.Max), formatFloat(rec.Mean),
+			rec.Last,
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// TimingsPruneCmd removes old or excess samples
+type TimingsPruneCmd struct {
+	StoreFlags `embed:""`
+
+	OlderThan  int `long:"older-than" help:"Remove samples older than N days (0: keep all)"`
+	MaxSamples int `long:"max-samples" help:"Keep at most N most recent samples per test (0: unlimited)"`
+}
+
+// Run prunes the database
+func (c *TimingsPruneCmd) Run() error {
+	if c.OlderThan <= 0 && c.MaxSamples <= 0 {
+		return fmt.Errorf("either --older-than or --max-samples is required")
+	}
+	db, err := history.Open(c.TimingsDB)
+	if err != nil {
+		return err
+	}
+	hist := db.Tests
+	var before time.Time
+	if c.OlderThan > 0 {
+		before = time.Now().AddDate(0, 0, -c.OlderThan)
+	}
+	keys := len(hist)
+	removed := hist.Prune(before, c.MaxSamples)
+	if err := history.Save(c.TimingsDB, db); err != nil {
+		return err
+	}
+	log.Printf("Pruned %d samples, removed %d of %d testcases from %s\n", removed, keys-len(hist), keys, c.TimingsDB)
+	return nil
+}
+
+type keyStats struct {
+	history.Stats
+	Key string
+}
+
+// loadStats summarizes the tests in the timing database matching filter, sorted by key
+func loadStats(db, filter string) ([]keyStats, error) {
+	var re *regexp.Regexp
+	if filter != "" {
+		var err error
+		if re, err = regexp.Compile(filter); err != nil {
+			return nil, fmt.Errorf("invalid filter pattern: %w", err)
+		}
+	}
+	hist, err := history.Load(db)
+	if err != nil {
+		return nil, err
+	}
+	stats := []keyStats{}
+	for _, k := range slices.Sorted(maps.Keys(hist)) {
+		if re != nil && !re.MatchString(k) {
+			continue
+		}
+		stats = append(stats, keyStats{Stats: history.Summarize(hist[k]), Key: k})
+	}
+	return stats, nil
+}
@@ -13,8 +13,8 @@ import (
 )
 
 func main() {
-	cli := &command.CLI{}
-	parser := kong.Must(cli, &kong.Vars{"version": fmt.Sprintf("testsplitter: %s", command.Version())},
+	app := &command.App{}
+	parser := kong.Must(app, &kong.Vars{"version": fmt.Sprintf("testsplitter: %s", command.Version())},
 		kong.Name("testsplitter"),
 		kong.Description("Split Go tests across multiple nodes."),
 		kong.ConfigureHelp(kong.HelpOptions{Compact: true}),
@@ -0,0 +1,220 @@
+// Package affected selects the packages whose tests are affected by the files
+// changed since a git revision, through the reverse import graph.
+package affected
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/takuo/go-testsplitter/internal/scanner"
+)
+
+// ChangedFiles returns the absolute paths of the files changed in the working
+// tree of the git repository of dir since the merge base of since and HEAD,
+// like the diff of a pull request, including uncommitted and untracked files.
+// Deleted files are included too.
+func ChangedFiles(dir, since string) ([]string, error) {
+	root, err := git(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	root = strings.TrimSpace(root)
+	// git resolves symbolic links, while go list reports the directories under dir as given
+	if abs, err := filepath.Abs(dir); err == nil {
+		if real, err := filepath.EvalSymlinks(abs); err == nil {
+			if r, err := filepath.Rel(real, root); err == nil {
+				root = filepath.Join(abs, r)
+			}
+		}
+	}
+	base := since
+	if mergeBase, err := git(dir, "merge-base", since, "HEAD"); err == nil {
+		base = strings.TrimSpace(mergeBase)
+	}
+	diff, err := git(dir, "diff", "--name-only", "-z", base, "--")
+	if err != nil {
+		return nil, err
+	}
+	untracked, err := git(dir, "ls-files", "--others", "--exclude-standard", "-z", "--full-name", ":/")
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, name := range strings.Split(diff+untracked, "\x00") {
+		if name != "" {
+			files = append(files, filepath.Join(root, filepath.FromSlash(name)))
+		}
+	}
+	slices.Sort(files)
+	return slices.Compact(files), nil
+}
+
+// git runs a git command in dir and returns its output
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("%w: failed to run git %s: %s", err, strings.Join(args, " "), strings.TrimSpace(stderr.String()))
+	}
+	return string(output), nil
+}
+
+// Reason explains why a package is affected: either files of the package
+// changed, or it imports an affected package
+type Reason struct {
+	Files []string // changed files, relative to the package directory or module
+	Via   string   // the affected package imported by the package, if no file changed
+}
+
+// Graph is the reverse import graph of the packages, including the imports of
+// their tests. A test variant of a package, and its external test package,
+// are merged into the package.
+type Graph struct {
+	importers map[string][]string // import path -> packages importing it
+	deps      map[string]scanner.Dependency
+}
+
+// NewGraph builds the reverse import graph from the output of go list -deps -test
+func NewGraph(deps []scanner.Dependency) *Graph {
+	g := &Graph{importers: make(map[string][]string), deps: make(map[string]scanner.Dependency)}
+	for _, dep := range deps {
+		pkg := node(dep.ImportPath)
+		if prev, ok := g.deps[pkg]; ok {
+			// the variants and the external test package share the directory
+			prev.Files = slices.Concat(prev.Files, dep.Files)
+			g.deps[pkg] = prev
+		} else {
+			dep.ImportPath = pkg
+			g.deps[pkg] = dep
+		}
+		for _, imp := range dep.Imports {
+			if imp := node(imp); imp != pkg && !slices.Contains(g.importers[imp], pkg) {
+				g.importers[imp] = append(g.importers[imp], pkg)
+			}
+		}
+	}
+	return g
+}
+
+// node returns the package a package of the import graph belongs to:
+// "foo [bar.test]" is foo compiled for the tests of bar, "foo_test [foo.test]"
+// is the external test package of foo and "foo.test" is the test main of foo.
+func node(importPath string) string {
+	pkg, variant, ok := strings.Cut(importPath, " [")
+	if !ok {
+		return strings.TrimSuffix(importPath, ".test")
+	}
+	forTest := strings.TrimSuffix(strings.TrimSuffix(variant, "]"), ".test")
+	if pkg == forTest+"_test" {
+		return forTest
+	}
+	return pkg
+}
+
+// Affected returns the packages affected by the changed files (absolute
+// paths) with the reasons. A file in a testdata directory affects every
+// package under the directory containing it, and a change of go.mod or go.sum
+// affects every package of the module, as do go.work and go.work.sum for
+// every package.
+func (g *Graph) Affected(changed []string) map[string]Reason {
+	reasons := make(map[string]Reason)
+	add := func(pkg, file string) {
+		r := reasons[pkg]
+		if !slices.Contains(r.Files, file) {
+			r.Files = append(r.Files, file)
+		}
+		reasons[pkg] = r
+	}
+	for _, file := range changed {
+		dir, name := filepath.Split(file)
+		dir = filepath.Clean(dir)
+		owner := testdataOwner(file)
+		for pkg, dep := range g.deps {
+			if dep.Dir == "" {
+				continue
+			}
+			switch {
+			case name == "go.work" || name == "go.work.sum":
+				add(pkg, file)
+			case (name == "go.mod" || name == "go.sum") && dep.ModuleDir == dir:
+				add(pkg, rel(dep.ModuleDir, file))
+			case dep.Dir == dir && (filepath.Ext(name) == ".go" || slices.Contains(dep.Files, name)):
+				// a removed Go file is no longer listed
+				add(pkg, name)
+			case within(dep.Dir, file) && slices.Contains(dep.Files, rel(dep.Dir, file)):
+				// embedded files may be in subdirectories
+				add(pkg, rel(dep.Dir, file))
+			case owner != "" && within(owner, dep.Dir):
+				add(pkg, rel(dep.ModuleDir, file))
+			}
+		}
+	}
+	for _, r := range reasons {
+		slices.Sort(r.Files)
+	}
+
+	// breadth first, so the reasons give the shortest import chains
+	queue := slices.Sorted(maps.Keys(reasons))
+	for len(queue) > 0 {
+		pkg := queue[0]
+		queue = queue[1:]
+		importers := slices.Clone(g.importers[pkg])
+		slices.Sort(importers)
+		for _, importer := range importers {
+			if _, ok := reasons[importer]; !ok {
+				reasons[importer] = Reason{Via: pkg}
+				queue = append(queue, importer)
+			}
+		}
+	}
+	return reasons
+}
+
+// Explain describes why the package is affected, following its imports to the changed package
+func Explain(reasons map[string]Reason, pkg string) string {
+	r, ok := reasons[pkg]
+	if !ok {
+		return "not affected"
+	}
+	if r.Via == "" {
+		return "changed " + strings.Join(r.Files, ", ")
+	}
+	chain := []string{r.Via}
+	for r = reasons[r.Via]; r.Via != ""; r = reasons[r.Via] {
+		chain = append(chain, r.Via)
+	}
+	return fmt.Sprintf("imports %s (changed %s)", strings.Join(chain, " -> "), strings.Join(r.Files, ", "))
+}
+
+// testdataOwner returns the directory containing the testdata directory of the
+// path, or "" if the path is not in testdata
+func testdataOwner(path string) string {
+	for dir := filepath.Dir(path); dir != filepath.Dir(dir); dir = filepath.Dir(dir) {
+		if filepath.Base(dir) == "testdata" {
+			return filepath.Dir(dir)
+		}
+	}
+	return ""
+}
+
+// rel returns path relative to dir, or path itself if not below dir
+func rel(dir, path string) string {
+	if r, err := filepath.Rel(dir, path); err == nil && filepath.IsLocal(r) {
+		return filepath.ToSlash(r)
+	}
+	return path
+}
+
+// within reports whether path is dir or below it
+func within(dir, path string) bool {
+	r, err := filepath.Rel(dir, path)
+	return err == nil && filepath.IsLocal(r)
+}
@@ -0,0 +1,139 @@
+package affected
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/takuo/go-testsplitter/internal/scanner"
+)
+
+func TestAffected(t *testing.T) {
+	// api imports store, whose external tests import fixtures; cli imports api
+	graph := NewGraph([]scanner.Dependency{
+		{ImportPath: "fmt", Dir: "/goroot/src/fmt", Files: []string{"print.go"}},
+		{ImportPath: "example.com/m/store", Dir: "/m/store", ModuleDir: "/m", Imports: []string{"fmt"}, Files: []string{"store.go", "schema.sql"}},
+		{ImportPath: "example.com/m/fixtures", Dir: "/m/fixtures", ModuleDir: "/m", Files: []string{"fixtures.go"}},
+		{ImportPath: "example.com/m/store [example.com/m/store.test]", Dir: "/m/store", ForTest: "example.com/m/store", ModuleDir: "/m", Imports: []string{"fmt"}, Files: []string{"store.go", "store_test.go"}},
+		{ImportPath: "example.com/m/store_test [example.com/m/store.test]", Dir: "/m/store", ForTest: "example.com/m/store", ModuleDir: "/m", Imports: []string{"example.com/m/store [example.com/m/store.test]", "example.com/m/fixtures"}, Files: []string{"export_test.go"}},
+		{ImportPath: "example.com/m/store.test", Dir: "/m/store", Imports: []string{"example.com/m/store [example.com/m/store.test]", "example.com/m/store_test [example.com/m/store.test]"}},
+		{ImportPath: "example.com/m/api", Dir: "/m/api", ModuleDir: "/m", Imports: []string{"example.com/m/store"}, Files: []string{"api.go", "static/index.html"}},
+		{ImportPath: "example.com/m/cli", Dir: "/m/cli", ModuleDir: "/m", Imports: []string{"example.com/m/api"}, Files: []string{"cli.go"}},
+		{ImportPath: "example.com/other/tool", Dir: "/other/tool", ModuleDir: "/other", Files: []string{"tool.go"}},
+	})
+
+	tests := []struct {
+		changed []string
+		want    map[string]string
+	}{
+		{
+			changed: []string{"/m/store/store.go"},
+			want: map[string]string{
+				"example.com/m/store": "changed store.go",
+				"example.com/m/api":   "imports example.com/m/store (changed store.go)",
+				"example.com/m/cli":   "imports example.com/m/api -> example.com/m/store (changed store.go)",
+			},
+		},
+		{
+			// the external tests of store import fixtures
+			changed: []string{"/m/fixtures/fixtures.go", "/m/README.md"},
+			want: map[string]string{
+				"example.com/m/fixtures": "changed fixtures.go",
+				"example.com/m/store":    "imports example.com/m/fixtures (changed fixtures.go)",
+				"example.com/m/api":      "imports example.com/m/store -> example.com/m/fixtures (changed fixtures.go)",
+				"example.com/m/cli":      "imports example.com/m/api -> example.com/m/store -> example.com/m/fixtures (changed fixtures.go)",
+			},
+		},
+		{
+			// embedded and test files, and a removed file
+			changed: []string{"/m/api/static/index.html", "/m/cli/removed.go", "/m/store/export_test.go"},
+			want: map[string]string{
+				"example.com/m/api":   "changed static/index.html",
+				"example.com/m/cli":   "changed removed.go",
+				"example.com/m/store": "changed export_test.go",
+			},
+		},
+		{
+			// testdata affects the packages under the directory containing it
+			changed: []string{"/m/testdata/golden.txt"},
+			want: map[string]string{
+				"example.com/m/store":    "changed testdata/golden.txt",
+				"example.com/m/fixtures": "changed testdata/golden.txt",
+				"example.com/m/api":      "changed testdata/golden.txt",
+				"example.com/m/cli":      "changed testdata/golden.txt",
+			},
+		},
+		{
+			changed: []string{"/other/go.sum"},
+			want: map[string]string{
+				"example.com/other/tool": "changed go.sum",
+			},
+		},
+	}
+	for _, tt := range tests {
+		reasons := graph.Affected(tt.changed)
+		got := make(map[string]string)
+		for pkg := range reasons {
+			got[pkg] = Explain(reasons, pkg)
+		}
+		assert.Equal(t, tt.want, got, "%v", tt.changed)
+	}
+
+	// go.work affects every package, including the standard library ones
+	assert.Len(t, graph.Affected([]string{"/go.work"}), 6)
+	assert.Equal(t, "not affected", Explain(nil, "example.com/m/cli"))
+}
+
+func TestChangedFiles(t *testing.T) {
+	dir := t.TempDir()
+	run := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com", "GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com")
+		output, err := cmd.CombinedOutput()
+		require.NoError(t, err, "git %v: %s", args, output)
+	}
+	write := func(name, content string) {
+		t.Helper()
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+
+	run("init", "-q", "-b", "main")
+	write("a/a.go", "package a\n")
+	write("b/b.go", "package b\n")
+	write("c/c.go", "package c\n")
+	write("e/e.go", "package e\n")
+	run("add", "-A")
+	run("commit", "-q", "-m", "initial")
+	run("checkout", "-q", "-b", "feature")
+	write("a/a.go", "package a\n\nvar X int\n")
+	run("commit", "-q", "-am", "change a")
+
+	// a commit on main after the branch point is not a change of the branch
+	run("checkout", "-q", "main")
+	write("e/e.go", "package e\n\nvar Y int\n")
+	run("commit", "-q", "-am", "change e")
+	run("checkout", "-q", "feature")
+
+	// uncommitted, removed and untracked files
+	write("b/b.go", "package b\n\nvar Z int\n")
+	require.NoError(t, os.Remove(filepath.Join(dir, "c", "c.go")))
+	write("d/d.go", "package d\n")
+
+	files, err := ChangedFiles(filepath.Join(dir, "a"), "main")
+	require.NoError(t, err)
+	for i, file := range files {
+		files[i], err = filepath.Rel(dir, file)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, []string{filepath.Join("a", "a.go"), filepath.Join("b", "b.go"), filepath.Join("c", "c.go"), filepath.Join("d", "d.go")}, files)
+
+	_, err = ChangedFiles(dir, "no-such-ref")
+	assert.Error(t, err)
+}
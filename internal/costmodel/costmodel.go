@@ -0,0 +1,108 @@
+// Package costmodel estimates how long a set of tests takes to run on a node.
+package costmodel
+
+import (
+	"cmp"
+	"slices"
+	"time"
+
+	"github.com/takuo/go-testsplitter/internal/history"
+)
+
+// Model computes the cost of the tests assigned to a node.
+// Every invocation of a test binary pays the package overhead (binary start-up
+// and TestMain), so the overhead is charged once per package and once more for
+// every additional chunk of MaxFunctions tests of the same package.
+//
+// Within an invocation, top-level tests calling t.Parallel() run after the
+// sequential ones, up to TestParallel at a time, so the invocation takes the
+// sum of the sequential tests plus the makespan of the parallel ones.
+type Model struct {
+	Durations    map[string]time.Duration // per test key
+	Overheads    map[string]time.Duration // per package
+	Standalone   map[string]bool          // keys always run in their own invocation
+	Parallel     map[string]bool          // keys of tests calling t.Parallel()
+	MaxFunctions int                      // 0: unlimited
+	TestParallel int                      // value of -test.parallel, 0 or 1: sequential
+}
+
+// Cost returns the expected duration of running the tests with the given keys
+func (m *Model) Cost(keys []string) time.Duration {
+	var pkgs []string
+	byPkg := make(map[string][]string)
+	for _, k := range keys {
+		pkg, _ := history.SplitKey(k)
+		if _, ok := byPkg[pkg]; !ok {
+			pkgs = append(pkgs, pkg)
+		}
+		byPkg[pkg] = append(byPkg[pkg], k)
+	}
+	var total time.Duration
+	for _, pkg := range pkgs {
+		total += m.PackageCost(pkg, byPkg[pkg])
+	}
+	return total
+}
+
+// PackageCost returns the expected duration of running the tests of a package
+// with the given keys. The cost of a node is the sum of the costs of its packages.
+func (m *Model) PackageCost(pkg string, keys []string) time.Duration {
+	var total time.Duration
+	invoked := keys
+	if slices.ContainsFunc(keys, func(k string) bool { return m.Standalone[k] }) {
+		invoked = nil
+		for _, k := range keys {
+			if m.Standalone[k] {
+				total += m.Overheads[pkg] + m.Durations[k]
+			} else {
+				invoked = append(invoked, k)
+			}
+		}
+	}
+	if len(invoked) == 0 {
+		return total
+	}
+	for _, invocation := range m.invocations(invoked) {
+		total += m.Overheads[pkg] + m.invocationCost(invocation)
+	}
+	return total
+}
+
+// invocationCost returns the duration of running the tests in a single test
+// binary invocation, excluding the package overhead
+func (m *Model) invocationCost(keys []string) time.Duration {
+	var sequential time.Duration
+	var parallel []time.Duration
+	for _, k := range keys {
+		if m.Parallel[k] && m.TestParallel > 1 {
+			parallel = append(parallel, m.Durations[k])
+		} else {
+			sequential += m.Durations[k]
+		}
+	}
+	return sequential + makespan(parallel, m.TestParallel)
+}
+
+// invocations splits the keys of a package into the chunks run by each invocation
+func (m *Model) invocations(keys []string) [][]string {
+	if m.MaxFunctions <= 0 {
+		return [][]string{keys}
+	}
+	return slices.Collect(slices.Chunk(keys, m.MaxFunctions))
+}
+
+// makespan returns the time to run the durations on the given number of
+// slots, assigning the longest first to the least loaded slot
+func makespan(durs []time.Duration, slots int) time.Duration {
+	if len(durs) == 0 {
+		return 0
+	}
+	sorted := slices.Clone(durs)
+	slices.SortFunc(sorted, func(a, b time.Duration) int { return cmp.Compare(b, a) })
+	loads := make([]time.Duration, min(slots, len(sorted)))
+	for _, d := range sorted {
+		i := slices.Index(loads, slices.Min(loads))
+		loads[i] += d
+	}
+	return slices.Max(loads)
+}
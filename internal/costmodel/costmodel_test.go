@@ -0,0 +1,60 @@
+package costmodel
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModelCost(t *testing.T) {
+	m := &Model{
+		Durations: map[string]time.Duration{
+			"pkg1:TestA": 1 * time.Second,
+			"pkg1:TestB": 2 * time.Second,
+			"pkg1:TestC": 3 * time.Second,
+			"pkg2:TestD": 4 * time.Second,
+		},
+		Overheads: map[string]time.Duration{
+			"pkg1": 10 * time.Second,
+		},
+	}
+
+	assert.Equal(t, time.Duration(0), m.Cost(nil))
+	assert.Equal(t, 16*time.Second, m.Cost([]string{"pkg1:TestA", "pkg1:TestB", "pkg1:TestC"}), "overhead once per package")
+	assert.Equal(t, 15*time.Second, m.Cost([]string{"pkg1:TestA", "pkg2:TestD"}), "no overhead known for pkg2")
+
+	m.Standalone = map[string]bool{"pkg1:TestC": true}
+	assert.Equal(t, 26*time.Second, m.Cost([]string{"pkg1:TestA", "pkg1:TestB", "pkg1:TestC"}), "standalone keys pay their own overhead")
+
+	m.Standalone = nil
+	m.MaxFunctions = 2
+	assert.Equal(t, 26*time.Second, m.Cost([]string{"pkg1:TestA", "pkg1:TestB", "pkg1:TestC"}), "overhead once per chunk")
+}
+
+func TestModelCost_Parallel(t *testing.T) {
+	m := &Model{
+		Durations: map[string]time.Duration{
+			"pkg:TestSerial": 1 * time.Second,
+			"pkg:TestP1":     4 * time.Second,
+			"pkg:TestP2":     3 * time.Second,
+			"pkg:TestP3":     2 * time.Second,
+			"pkg:TestP4":     1 * time.Second,
+		},
+		Parallel: map[string]bool{
+			"pkg:TestP1": true,
+			"pkg:TestP2": true,
+			"pkg:TestP3": true,
+			"pkg:TestP4": true,
+		},
+	}
+	keys := []string{"pkg:TestSerial", "pkg:TestP1", "pkg:TestP2", "pkg:TestP3", "pkg:TestP4"}
+
+	assert.Equal(t, 11*time.Second, m.Cost(keys), "without -test.parallel everything is sequential")
+
+	m.TestParallel = 2
+	assert.Equal(t, 6*time.Second, m.Cost(keys), "1s sequential + 5s on two slots (4+1, 3+2)")
+
+	m.TestParallel = 8
+	assert.Equal(t, 5*time.Second, m.Cost(keys), "1s sequential + longest parallel test")
+}
@@ -0,0 +1,165 @@
+// Package estimate provides duration estimators for tests without history.
+package estimate
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Strategy names
+const (
+	StrategyFixed         = "fixed"
+	StrategyPackageMean   = "package-mean"
+	StrategyPackageMedian = "package-median"
+	StrategyFile          = "file"
+	StrategySize          = "size"
+)
+
+// Unit describes a test function for estimation
+type Unit struct {
+	Package  string
+	Function string
+	File     string
+	Stmts    int
+}
+
+// DurationEstimator estimates the duration of a test without history
+type DurationEstimator interface {
+	Estimate(u Unit) time.Duration
+}
+
+// Known is a test with a known duration
+type Known struct {
+	Unit
+	Duration time.Duration
+}
+
+// New returns the estimator for the strategy, learning from the known tests.
+// Every strategy falls back to the fixed duration when it has nothing to learn from.
+func New(strategy string, known []Known, fixed time.Duration) (DurationEstimator, error) {
+	fallback := Fixed(fixed)
+	switch strategy {
+	case "", StrategyFixed:
+		return fallback, nil
+	case StrategyPackageMean:
+		return newGrouped(known, packageOf, mean, fallback), nil
+	case StrategyPackageMedian:
+		return newGrouped(known, packageOf, median, fallback), nil
+	case StrategyFile:
+		// neighbours in the same file first, then the whole package
+		return newGrouped(known, fileOf, mean, newGrouped(known, packageOf, mean, fallback)), nil
+	case StrategySize:
+		return newSize(known, fallback), nil
+	default:
+		return nil, fmt.Errorf("unknown estimation strategy: %s", strategy)
+	}
+}
+
+// Fixed estimates every test with the same duration
+type Fixed time.Duration
+
+// Estimate implements DurationEstimator
+func (f Fixed) Estimate(Unit) time.Duration {
+	return time.Duration(f)
+}
+
+// grouped estimates a test from the known tests in the same group
+type grouped struct {
+	group    func(Unit) string
+	values   map[string]time.Duration
+	fallback DurationEstimator
+}
+
+func newGrouped(known []Known, group func(Unit) string, reduce func([]time.Duration) time.Duration, fallback DurationEstimator) *grouped {
+	durs := make(map[string][]time.Duration)
+	for _, k := range known {
+		g := group(k.Unit)
+		durs[g] = append(durs[g], k.Duration)
+	}
+	values := make(map[string]time.Duration, len(durs))
+	for g, d := range durs {
+		values[g] = reduce(d)
+	}
+	return &grouped{group: group, values: values, fallback: fallback}
+}
+
+// Estimate implements DurationEstimator
+func (g *grouped) Estimate(u Unit) time.Duration {
+	if d, ok := g.values[g.group(u)]; ok {
+		return d
+	}
+	return g.fallback.Estimate(u)
+}
+
+func packageOf(u Unit) string { return u.Package }
+
+func fileOf(u Unit) string {
+	if u.File == "" {
+		return ""
+	}
+	return u.Package + ":" + u.File
+}
+
+func mean(durs []time.Duration) time.Duration {
+	var sum time.Duration
+	for _, d := range durs {
+		sum += d
+	}
+	return sum / time.Duration(len(durs))
+}
+
+func median(durs []time.Duration) time.Duration {
+	sorted := slices.Sorted(slices.Values(durs))
+	return sorted[(len(sorted)-1)/2]
+}
+
+// size estimates a test proportionally to the number of statements in its body,
+// using the time per statement learned from the known tests of the package
+// (or of all packages when the package has no known tests).
+type size struct {
+	perStmt  map[string]float64
+	global   float64
+	fallback DurationEstimator
+}
+
+func newSize(known []Known, fallback DurationEstimator) *size {
+	type acc struct {
+		dur   time.Duration
+		stmts int
+	}
+	var total acc
+	pkgs := make(map[string]*acc)
+	for _, k := range known {
+		a, ok := pkgs[k.Package]
+		if !ok {
+			a = &acc{}
+			pkgs[k.Package] = a
+		}
+		stmts := max(k.Stmts, 1)
+		a.dur += k.Duration
+		a.stmts += stmts
+		total.dur += k.Duration
+		total.stmts += stmts
+	}
+	s := &size{perStmt: make(map[string]float64, len(pkgs)), fallback: fallback}
+	for pkg, a := range pkgs {
+		s.perStmt[pkg] = float64(a.dur) / float64(a.stmts)
+	}
+	if total.stmts > 0 {
+		s.global = float64(total.dur) / float64(total.stmts)
+	}
+	return s
+}
+
+// Estimate implements DurationEstimator
+func (s *size) Estimate(u Unit) time.Duration {
+	rate, ok := s.perStmt[u.Package]
+	if !ok {
+		rate = s.global
+	}
+	if rate == 0 {
+		return s.fallback.Estimate(u)
+	}
+	return time.Duration(rate * float64(max(u.Stmts, 1)))
+}
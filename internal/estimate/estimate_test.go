@@ -0,0 +1,54 @@
+package estimate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	known := []Known{
+		{Unit{Package: "pkg1", Function: "TestA", File: "a_test.go", Stmts: 10}, 13 * time.Second},
+		{Unit{Package: "pkg1", Function: "TestB", File: "a_test.go", Stmts: 10}, 2 * time.Second},
+		{Unit{Package: "pkg1", Function: "TestC", File: "c_test.go", Stmts: 20}, 3 * time.Second},
+		{Unit{Package: "pkg2", Function: "TestD", File: "d_test.go", Stmts: 5}, 900 * time.Millisecond},
+	}
+	sameFile := Unit{Package: "pkg1", Function: "TestNew", File: "a_test.go", Stmts: 4}
+	otherFile := Unit{Package: "pkg1", Function: "TestNew", File: "new_test.go", Stmts: 4}
+	newPkg := Unit{Package: "pkg3", Function: "TestNew", File: "new_test.go", Stmts: 9}
+
+	tests := []struct {
+		strategy string
+		unit     Unit
+		want     time.Duration
+	}{
+		{StrategyFixed, sameFile, 5 * time.Second},
+		{StrategyPackageMean, otherFile, 6 * time.Second},
+		{StrategyPackageMean, newPkg, 5 * time.Second},
+		{StrategyPackageMedian, otherFile, 3 * time.Second},
+		{StrategyFile, sameFile, 7500 * time.Millisecond},
+		{StrategyFile, otherFile, 6 * time.Second},
+		{StrategySize, otherFile, 1800 * time.Millisecond}, // 18s / 40 stmts in pkg1
+		{StrategySize, newPkg, 3780 * time.Millisecond},    // 18.9s / 45 stmts in all packages
+	}
+	for _, tt := range tests {
+		t.Run(tt.strategy+"/"+tt.unit.Package+"/"+tt.unit.File, func(t *testing.T) {
+			est, err := New(tt.strategy, known, 5*time.Second)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, est.Estimate(tt.unit))
+		})
+	}
+
+	_, err := New("unknown", known, 5*time.Second)
+	assert.Error(t, err)
+}
+
+func TestNew_NoHistory(t *testing.T) {
+	for _, strategy := range []string{StrategyFixed, StrategyPackageMean, StrategyPackageMedian, StrategyFile, StrategySize} {
+		est, err := New(strategy, nil, time.Second)
+		require.NoError(t, err)
+		assert.Equal(t, time.Second, est.Estimate(Unit{Package: "pkg", Function: "TestA"}), strategy)
+	}
+}
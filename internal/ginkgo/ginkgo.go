@@ -0,0 +1,126 @@
+// Package ginkgo lists the specs of Ginkgo v2 suites and reads their durations
+// from Ginkgo JSON reports, so a suite can be split across nodes by specs.
+package ginkgo
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/takuo/go-testsplitter/internal/history"
+)
+
+// Report is a suite report in a Ginkgo JSON report (--ginkgo.json-report),
+// restricted to the fields used here
+type Report struct {
+	SuitePath        string
+	SuiteDescription string
+	SpecReports      []SpecReport
+}
+
+// SpecReport is the report of a spec or of a suite-level node
+type SpecReport struct {
+	ContainerHierarchyTexts []string
+	LeafNodeType            string
+	LeafNodeText            string
+	State                   string
+	RunTime                 time.Duration
+	EndTime                 time.Time
+}
+
+// Text returns the full text of the spec, which --ginkgo.focus and
+// --ginkgo.skip match against
+func (s *SpecReport) Text() string {
+	return strings.Join(append(s.ContainerHierarchyTexts, s.LeafNodeText), " ")
+}
+
+// IsSpec reports whether the report is a spec rather than a suite-level node (e.g. BeforeSuite)
+func (s *SpecReport) IsSpec() bool {
+	return s.LeafNodeType == "It"
+}
+
+// DecodeReport decodes a Ginkgo JSON report
+func DecodeReport(r io.Reader) ([]Report, error) {
+	var reports []Report
+	if err := json.NewDecoder(r).Decode(&reports); err != nil {
+		return nil, fmt.Errorf("failed to decode Ginkgo report: %w", err)
+	}
+	return reports, nil
+}
+
+// SpecKey returns the history key of a spec of the suite in pkg.
+// Specs are recorded as subtests of a pseudo test function, so they are not
+// mistaken for top-level tests.
+func SpecKey(pkg, spec string) string {
+	return history.Key(pkg, "(ginkgo)/"+spec)
+}
+
+// ParseReport reads the spec durations of a Ginkgo JSON report.
+// The package of a suite is its suite path, the absolute directory of the
+// package where the report was written, to be matched by the caller.
+func ParseReport(r io.Reader) (history.History, error) {
+	reports, err := DecodeReport(r)
+	if err != nil {
+		return nil, err
+	}
+	results := make(history.History)
+	for _, report := range reports {
+		pkg := filepath.ToSlash(report.SuitePath)
+		for _, spec := range report.SpecReports {
+			if !spec.IsSpec() || spec.State == "pending" || spec.State == "skipped" {
+				continue
+			}
+			sample := history.Sample{Duration: spec.RunTime, Time: spec.EndTime}
+			switch spec.State {
+			case "passed":
+				sample.Outcome = history.OutcomePass
+			default:
+				sample.Outcome = history.OutcomeFail
+			}
+			results.Add(SpecKey(pkg, spec.Text()), sample)
+		}
+	}
+	return results, nil
+}
+
+// ListSpecs lists the specs run by the test function of a suite by running
+// the test binary in dir with --ginkgo.dry-run
+func ListSpecs(binary, dir, function string) ([]string, error) {
+	tmp, err := os.CreateTemp("", "ginkgo-report-*.json")
+	if err != nil {
+		return nil, err
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+
+	cmd := exec.Command(binary, "-test.run", "^"+function+"$", "-ginkgo.dry-run", "-ginkgo.json-report="+tmp.Name(), "-ginkgo.no-color")
+	cmd.Dir = dir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("dry run of %s failed: %w: %s", binary, err, output)
+	}
+
+	fp, err := os.Open(tmp.Name())
+	if err != nil {
+		return nil, err
+	}
+	defer fp.Close()
+	reports, err := DecodeReport(fp)
+	if err != nil {
+		return nil, err
+	}
+	var specs []string
+	for _, report := range reports {
+		for _, spec := range report.SpecReports {
+			if spec.IsSpec() && spec.State != "pending" && spec.State != "skipped" && !slices.Contains(specs, spec.Text()) {
+				specs = append(specs, spec.Text())
+			}
+		}
+	}
+	return specs, nil
+}
@@ -0,0 +1,55 @@
+package ginkgo
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/takuo/go-testsplitter/internal/history"
+)
+
+const report = `[{
+  "SuitePath": "%s",
+  "SuiteDescription": "Books Suite",
+  "SpecReports": [
+    {"ContainerHierarchyTexts": null, "LeafNodeType": "BeforeSuite", "LeafNodeText": "", "State": "passed", "RunTime": 1000000000},
+    {"ContainerHierarchyTexts": ["Books", "when long"], "LeafNodeType": "It", "LeafNodeText": "is a novel", "State": "passed", "RunTime": 2500000000, "EndTime": "2025-01-01T00:00:02Z"},
+    {"ContainerHierarchyTexts": ["Books"], "LeafNodeType": "It", "LeafNodeText": "has a title", "State": "failed", "RunTime": 500000000},
+    {"ContainerHierarchyTexts": ["Books"], "LeafNodeType": "It", "LeafNodeText": "is pending", "State": "pending", "RunTime": 0}
+  ]
+}]`
+
+func TestParseReport(t *testing.T) {
+	h, err := ParseReport(strings.NewReader(strings.Replace(report, "%s", "/work/mod/books", 1)))
+	require.NoError(t, err)
+	assert.Equal(t, history.History{
+		"/work/mod/books:(ginkgo)/Books when long is a novel": {{Duration: 2500 * time.Millisecond, Time: time.Date(2025, 1, 1, 0, 0, 2, 0, time.UTC), Outcome: history.OutcomePass}},
+		"/work/mod/books:(ginkgo)/Books has a title":          {{Duration: 500 * time.Millisecond, Outcome: history.OutcomeFail}},
+	}, h)
+}
+
+func TestListSpecs(t *testing.T) {
+	dir := t.TempDir()
+	// a fake test binary writing the dry-run report
+	binary := filepath.Join(dir, "books.test")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "report.json"), []byte(strings.Replace(report, "%s", dir, 1)), 0o644))
+	require.NoError(t, os.WriteFile(binary, []byte(`#!/bin/sh
+for arg in "$@"; do
+  case "$arg" in
+    -ginkgo.json-report=*) cp report.json "${arg#-ginkgo.json-report=}" ;;
+  esac
+done
+`), 0o755))
+
+	specs, err := ListSpecs(binary, dir, "TestBooks")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Books when long is a novel", "Books has a title"}, specs)
+
+	_, err = ListSpecs(filepath.Join(dir, "missing.test"), dir, "TestBooks")
+	assert.Error(t, err)
+}
@@ -0,0 +1,357 @@
+// Package history keeps every observed test duration and reduces them to a single estimate.
+package history
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Sample is a single observation of a test execution
+type Sample struct {
+	Duration time.Duration
+	Time     time.Time // time the test finished, zero if unknown
+	Parallel bool      // the test was paused by t.Parallel()
+	Outcome  string    // OutcomePass, OutcomeFail, OutcomeSkip or empty if unknown
+	Rerun    bool      // the test had already failed earlier in the same run (e.g. gotestsum --rerun-fails)
+	// ID identifies a sample read from a report without times (e.g. JUnit XML),
+	// so the same report read again is not counted twice; empty if unknown
+	ID string
+}
+
+// Test outcomes
+const (
+	OutcomePass = "pass"
+	OutcomeFail = "fail"
+	OutcomeSkip = "skip"
+)
+
+// History holds all observations keyed by "package:Function"
+type History map[string][]Sample
+
+// Key returns the history key for a test function in a package
+func Key(pkg, fn string) string {
+	return pkg + ":" + fn
+}
+
+// PackageKey returns the history key for the per-invocation overhead of a package
+// (test binary start-up and TestMain), which is the key with an empty function.
+func PackageKey(pkg string) string {
+	return Key(pkg, "")
+}
+
+// IsPackageKey reports whether key is a package overhead key
+func IsPackageKey(key string) bool {
+	return strings.HasSuffix(key, ":")
+}
+
+// SplitKey splits a history key into package and function
+func SplitKey(key string) (pkg, fn string) {
+	pkg, fn, _ = strings.Cut(key, ":")
+	return pkg, fn
+}
+
+// Add appends samples for the key
+func (h History) Add(key string, samples ...Sample) {
+	h[key] = append(h[key], samples...)
+}
+
+// Merge appends all samples of other into h.
+// Samples with a time or an ID that are already present in h are skipped, so
+// the same result read both from the timing database and from a raw file counts once.
+func (h History) Merge(other History) {
+	type sampleID struct {
+		dur  time.Duration
+		nano int64
+	}
+	// Parallel is not part of the identity as JUnit reports do not record it
+	for k, samples := range other {
+		seen := make(map[sampleID]bool, len(h[k]))
+		seenIDs := make(map[string]bool)
+		for _, s := range h[k] {
+			if s.ID != "" {
+				seenIDs[s.ID] = true
+			} else if !s.Time.IsZero() {
+				seen[sampleID{s.Duration, s.Time.UnixNano()}] = true
+			}
+		}
+		for _, s := range samples {
+			switch {
+			case s.ID != "" && seenIDs[s.ID]:
+				continue
+			case s.ID == "" && !s.Time.IsZero() && seen[sampleID{s.Duration, s.Time.UnixNano()}]:
+				continue
+			}
+			h.Add(k, s)
+		}
+	}
+}
+
+// Prune removes samples that finished before the given time and keeps at most
+// maxSamples of the most recent samples per key. A zero before or a maxSamples
+// of 0 disables the respective limit. Samples without a time are never
+// considered older than before, nor older than the samples added before them.
+// It returns the number of removed samples.
+func (h History) Prune(before time.Time, maxSamples int) (removed int) {
+	for k, samples := range h {
+		kept := make([]Sample, 0, len(samples))
+		for _, s := range samples {
+			if !before.IsZero() && !s.Time.IsZero() && s.Time.Before(before) {
+				continue
+			}
+			kept = append(kept, s)
+		}
+		if maxSamples > 0 && len(kept) > maxSamples {
+			kept = mostRecent(kept, maxSamples)
+		}
+		removed += len(samples) - len(kept)
+		if len(kept) == 0 {
+			delete(h, k)
+		} else {
+			h[k] = kept
+		}
+	}
+	return removed
+}
+
+// mostRecent returns the n most recent samples, in order of time. The samples
+// are in insertion order, so a sample without a time is as recent as the most
+// recent sample added before it, e.g. a JUnit result without a timestamp.
+func mostRecent(samples []Sample, n int) []Sample {
+	type ordered struct {
+		Sample
+		at time.Time
+	}
+	order := make([]ordered, len(samples))
+	var latest time.Time
+	for i, s := range samples {
+		if s.Time.After(latest) {
+			latest = s.Time
+		}
+		order[i] = ordered{s, s.Time}
+		if s.Time.IsZero() {
+			order[i].at = latest
+		}
+	}
+	slices.SortStableFunc(order, func(a, b ordered) int { return a.at.Compare(b.at) })
+	kept := make([]Sample, 0, n)
+	for _, o := range order[len(order)-n:] {
+		kept = append(kept, o.Sample)
+	}
+	return kept
+}
+
+// Rename moves the samples of a test and of its subtests to another test
+func (h History) Rename(from, to string) {
+	moved := make(History)
+	for k, samples := range h {
+		if rest, ok := strings.CutPrefix(k, from); ok && (rest == "" || rest[0] == '/') {
+			delete(h, k)
+			moved[to+rest] = samples
+		}
+	}
+	for k, samples := range moved {
+		h.Add(k, samples...)
+	}
+}
+
+// RenamePackages moves the samples recorded under other names of packages,
+// e.g. their directories, to the canonical names given by aliases
+func (h History) RenamePackages(aliases map[string]string) (renamed int) {
+	moved := make(History)
+	for k, samples := range h {
+		pkg, fn := SplitKey(k)
+		if to, ok := aliases[pkg]; ok && to != pkg {
+			delete(h, k)
+			moved[Key(to, fn)] = samples
+			renamed++
+		}
+	}
+	h.Merge(moved)
+	return renamed
+}
+
+// Remove deletes a test and its subtests, returning the number of removed keys
+func (h History) Remove(key string) (removed int) {
+	for k := range h {
+		if rest, ok := strings.CutPrefix(k, key); ok && (rest == "" || rest[0] == '/') {
+			delete(h, k)
+			removed++
+		}
+	}
+	return removed
+}
+
+// Parallel reports whether any sample of the key ran in parallel
+func (h History) Parallel(key string) bool {
+	for _, s := range h[key] {
+		if s.Parallel {
+			return true
+		}
+	}
+	return false
+}
+
+// Durations reduces every key to a single duration using the estimator
+func (h History) Durations(est Estimator) map[string]time.Duration {
+	durations := make(map[string]time.Duration, len(h))
+	for k, samples := range h {
+		if len(samples) == 0 {
+			continue
+		}
+		durations[k] = est(samples)
+	}
+	return durations
+}
+
+// Stats summarizes the samples of a test
+type Stats struct {
+	Samples int
+	Min     time.Duration
+	Median  time.Duration
+	P90     time.Duration
+	Max     time.Duration
+	Mean    time.Duration
+	Last    time.Time // zero if no sample has a time
+}
+
+// Summarize computes the statistics of a non-empty list of samples
+func Summarize(samples []Sample) Stats {
+	durs := sortedDurations(samples)
+	st := Stats{
+		Samples: len(samples),
+		Min:     durs[0],
+		Median:  Percentile(50)(samples),
+		P90:     Percentile(90)(samples),
+		Max:     durs[len(durs)-1],
+	}
+	var sum time.Duration
+	for _, s := range samples {
+		sum += s.Duration
+		if s.Time.After(st.Last) {
+			st.Last = s.Time
+		}
+	}
+	st.Mean = sum / time.Duration(len(samples))
+	return st
+}
+
+// Outcomes summarizes the results of the runs of a test
+type Outcomes struct {
+	Runs        int       // first attempts
+	Failures    int       // first attempts that failed
+	Flakes      int       // reruns that passed after a failure in the same run
+	LastFailure time.Time // zero if no failure has a time
+}
+
+// FlakeRate returns the share of runs that passed only on a rerun
+func (o Outcomes) FlakeRate() float64 {
+	if o.Runs == 0 {
+		return 0
+	}
+	return float64(o.Flakes) / float64(o.Runs)
+}
+
+// SummarizeOutcomes counts the outcomes of the samples
+func SummarizeOutcomes(samples []Sample) Outcomes {
+	var o Outcomes
+	for _, s := range samples {
+		switch {
+		case s.Rerun && s.Outcome == OutcomePass:
+			o.Flakes++
+		case !s.Rerun && s.Outcome != "":
+			o.Runs++
+			if s.Outcome == OutcomeFail {
+				o.Failures++
+			}
+		}
+		if s.Outcome == OutcomeFail && s.Time.After(o.LastFailure) {
+			o.LastFailure = s.Time
+		}
+	}
+	return o
+}
+
+// Estimator reduces a non-empty list of samples to a single duration
+type Estimator func(samples []Sample) time.Duration
+
+// Estimator names
+const (
+	EstimatorMedian = "median"
+	EstimatorP90    = "p90"
+	EstimatorMax    = "max"
+	EstimatorEWMA   = "ewma"
+)
+
+// NewEstimator returns the estimator for name.
+// halfLife is used only by the "ewma" estimator.
+func NewEstimator(name string, halfLife time.Duration) (Estimator, error) {
+	switch name {
+	case "", EstimatorMedian:
+		return Percentile(50), nil
+	case EstimatorP90:
+		return Percentile(90), nil
+	case EstimatorMax:
+		return Max, nil
+	case EstimatorEWMA:
+		if halfLife <= 0 {
+			return nil, fmt.Errorf("half-life must be positive: %s", halfLife)
+		}
+		return EWMA(halfLife), nil
+	default:
+		return nil, fmt.Errorf("unknown estimator: %s", name)
+	}
+}
+
+// Percentile returns an estimator picking the p-th percentile (nearest rank) of the samples
+func Percentile(p float64) Estimator {
+	return func(samples []Sample) time.Duration {
+		durs := sortedDurations(samples)
+		rank := int(math.Ceil(p / 100 * float64(len(durs))))
+		return durs[max(rank-1, 0)]
+	}
+}
+
+// Max picks the longest sample
+func Max(samples []Sample) time.Duration {
+	var longest time.Duration
+	for _, s := range samples {
+		longest = max(longest, s.Duration)
+	}
+	return longest
+}
+
+// EWMA returns an estimator computing the mean of the samples weighted by their age.
+// The weight halves every halfLife, measured back from the most recent sample.
+// Samples without a time are treated as being as recent as the newest one.
+func EWMA(halfLife time.Duration) Estimator {
+	return func(samples []Sample) time.Duration {
+		var newest time.Time
+		for _, s := range samples {
+			if s.Time.After(newest) {
+				newest = s.Time
+			}
+		}
+		var sum, weights float64
+		for _, s := range samples {
+			w := 1.0
+			if !s.Time.IsZero() {
+				w = math.Exp2(-float64(newest.Sub(s.Time)) / float64(halfLife))
+			}
+			sum += w * float64(s.Duration)
+			weights += w
+		}
+		return time.Duration(sum / weights)
+	}
+}
+
+func sortedDurations(samples []Sample) []time.Duration {
+	durs := make([]time.Duration, len(samples))
+	for i, s := range samples {
+		durs[i] = s.Duration
+	}
+	slices.Sort(durs)
+	return durs
+}
@@ -0,0 +1,162 @@
+package history
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEstimators(t *testing.T) {
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	samples := []Sample{
+		{Duration: 1 * time.Second, Time: base},
+		{Duration: 9 * time.Second, Time: base.Add(time.Hour)},
+		{Duration: 2 * time.Second, Time: base.Add(2 * time.Hour)},
+		{Duration: 3 * time.Second, Time: base.Add(3 * time.Hour)},
+		{Duration: 4 * time.Second, Time: base.Add(4 * time.Hour)},
+	}
+
+	tests := []struct {
+		name string
+		want time.Duration
+	}{
+		{EstimatorMedian, 3 * time.Second},
+		{EstimatorP90, 9 * time.Second},
+		{EstimatorMax, 9 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			est, err := NewEstimator(tt.name, time.Hour)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, est(samples))
+		})
+	}
+
+	_, err := NewEstimator("unknown", time.Hour)
+	assert.Error(t, err)
+}
+
+func TestEWMA(t *testing.T) {
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	est := EWMA(time.Hour)
+
+	// one half-life apart: weights 1 and 0.5
+	got := est([]Sample{
+		{Duration: 4 * time.Second, Time: base},
+		{Duration: 1 * time.Second, Time: base.Add(time.Hour)},
+	})
+	assert.Equal(t, 2*time.Second, got)
+
+	// identical times behave like a plain mean
+	got = est([]Sample{{Duration: time.Second}, {Duration: 3 * time.Second}})
+	assert.Equal(t, 2*time.Second, got)
+}
+
+func TestHistoryMerge(t *testing.T) {
+	h := History{"pkg:TestA": {{Duration: time.Second}}}
+	h.Merge(History{
+		"pkg:TestA": {{Duration: 3 * time.Second}},
+		"pkg:TestB": {{Duration: 2 * time.Second}},
+	})
+	assert.Len(t, h["pkg:TestA"], 2)
+
+	durations := h.Durations(Max)
+	assert.Equal(t, map[string]time.Duration{
+		"pkg:TestA": 3 * time.Second,
+		"pkg:TestB": 2 * time.Second,
+	}, durations)
+}
+
+func TestRenamePackages(t *testing.T) {
+	h := History{
+		"pkg:TestA":                   {{Duration: time.Second}},
+		"pkg:TestA/sub":               {{Duration: time.Second}},
+		"pkg:":                        {{Duration: time.Second}},
+		"example.com/mod/pkg:TestA":   {{Duration: 2 * time.Second}},
+		"example.com/mod/other:TestB": {{Duration: 3 * time.Second}},
+		"unrelated/pkg:TestC":         {{Duration: 4 * time.Second}},
+	}
+	renamed := h.RenamePackages(map[string]string{"pkg": "example.com/mod/pkg", "other": "example.com/mod/other"})
+
+	assert.Equal(t, 3, renamed)
+	assert.Equal(t, History{
+		"example.com/mod/pkg:TestA":     {{Duration: 2 * time.Second}, {Duration: time.Second}},
+		"example.com/mod/pkg:TestA/sub": {{Duration: time.Second}},
+		"example.com/mod/pkg:":          {{Duration: time.Second}},
+		"example.com/mod/other:TestB":   {{Duration: 3 * time.Second}},
+		"unrelated/pkg:TestC":           {{Duration: 4 * time.Second}},
+	}, h)
+}
+
+func TestPrune(t *testing.T) {
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	h := History{
+		"pkg:TestA": {
+			{Duration: 1 * time.Second, Time: base},
+			{Duration: 2 * time.Second, Time: base.Add(2 * time.Hour)},
+			{Duration: 3 * time.Second, Time: base.Add(time.Hour)},
+			{Duration: 4 * time.Second},
+		},
+		"pkg:TestOld": {{Duration: time.Second, Time: base}},
+	}
+
+	removed := h.Prune(base.Add(time.Minute), 2)
+	assert.Equal(t, 3, removed)
+	assert.NotContains(t, h, "pkg:TestOld")
+	assert.Equal(t, []Sample{
+		{Duration: 2 * time.Second, Time: base.Add(2 * time.Hour)},
+		{Duration: 4 * time.Second},
+	}, h["pkg:TestA"], "a sample without a time is as recent as the ones added before it")
+
+	// samples without a time are pruned in insertion order
+	h = History{"pkg:TestB": {{Duration: 1 * time.Second}, {Duration: 2 * time.Second}, {Duration: 3 * time.Second}}}
+	assert.Equal(t, 1, h.Prune(time.Time{}, 2))
+	assert.Equal(t, []Sample{{Duration: 2 * time.Second}, {Duration: 3 * time.Second}}, h["pkg:TestB"])
+}
+
+func TestStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "timings", "timings.db")
+
+	h, err := Load(path)
+	require.NoError(t, err, "missing database should not be an error")
+	assert.Empty(t, h)
+
+	base := time.Date(2025, 1, 1, 0, 0, 0, 123, time.UTC)
+	want := History{
+		"pkg:TestA": {{Duration: 1500 * time.Millisecond, Time: base}, {Duration: time.Second}},
+		"pkg:TestB": {{Duration: 2 * time.Second, Time: base, Parallel: true, Outcome: OutcomePass, Rerun: true}},
+	}
+	fingerprints := map[string]string{"pkg:TestA": "0123456789abcdef"}
+	require.NoError(t, Save(path, &DB{Tests: want, Fingerprints: fingerprints}))
+
+	db, err := Open(path)
+	require.NoError(t, err)
+	assert.Equal(t, fingerprints, db.Fingerprints)
+
+	got, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+
+	// merging the same results again does not duplicate samples with a time
+	got.Merge(want)
+	assert.Len(t, got["pkg:TestA"], 3)
+	assert.Len(t, got["pkg:TestB"], 1)
+}
+
+func TestSummarizeOutcomes(t *testing.T) {
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	o := SummarizeOutcomes([]Sample{
+		{Outcome: OutcomePass, Time: base},
+		{Outcome: OutcomeFail, Time: base.Add(time.Hour)},
+		{Outcome: OutcomePass, Time: base.Add(time.Hour), Rerun: true},
+		{Outcome: OutcomeFail, Time: base.Add(2 * time.Hour)},
+		{Outcome: OutcomeFail, Time: base.Add(2 * time.Hour), Rerun: true},
+		{Outcome: OutcomePass, Time: base.Add(3 * time.Hour)},
+		{Time: base.Add(4 * time.Hour)}, // unknown outcome
+	})
+	assert.Equal(t, Outcomes{Runs: 4, Failures: 2, Flakes: 1, LastFailure: base.Add(2 * time.Hour)}, o)
+	assert.Equal(t, 0.25, o.FlakeRate())
+}
@@ -0,0 +1,152 @@
+package history
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// storeVersion is the version of the on-disk timing database format
+const storeVersion = 1
+
+// storeFile is the on-disk representation of a History.
+// It is stored as gzip-compressed JSON with short field names to keep it compact.
+type storeFile struct {
+	Version      int                       `json:"version"`
+	Tests        map[string][]storedSample `json:"tests"`
+	Fingerprints map[string]string         `json:"fingerprints,omitempty"`
+}
+
+type storedSample struct {
+	Duration int64  `json:"d"`           // nanoseconds
+	Time     int64  `json:"t,omitempty"` // unix nanoseconds
+	Parallel bool   `json:"p,omitempty"`
+	Outcome  string `json:"o,omitempty"`
+	Rerun    bool   `json:"r,omitempty"`
+	ID       string `json:"i,omitempty"`
+}
+
+// DB is the content of the timing database
+type DB struct {
+	Tests History
+	// Fingerprints of the test function bodies keyed by "package:Function",
+	// used to carry the history of renamed tests over
+	Fingerprints map[string]string
+}
+
+// Load reads the history from a timing database written by Save.
+// A missing file is not an error and results in an empty History.
+func Load(path string) (History, error) {
+	db, err := Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return db.Tests, nil
+}
+
+// Open reads a timing database written by Save.
+// A missing file is not an error and results in an empty DB.
+func Open(path string) (*DB, error) {
+	h := make(History)
+	db := &DB{Tests: h, Fingerprints: make(map[string]string)}
+	fp, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return db, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer fp.Close()
+
+	zr, err := gzip.NewReader(fp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read timing database %s: %w", path, err)
+	}
+	defer zr.Close()
+
+	var data storeFile
+	if err := json.NewDecoder(zr).Decode(&data); err != nil {
+		return nil, fmt.Errorf("failed to decode timing database %s: %w", path, err)
+	}
+	if data.Version != storeVersion {
+		return nil, fmt.Errorf("unsupported timing database version %d in %s", data.Version, path)
+	}
+	for k, samples := range data.Tests {
+		for _, s := range samples {
+			sample := Sample{
+				Duration: time.Duration(s.Duration),
+				Parallel: s.Parallel,
+				Outcome:  s.Outcome,
+				Rerun:    s.Rerun,
+				ID:       s.ID,
+			}
+			if s.Time != 0 {
+				sample.Time = time.Unix(0, s.Time).UTC()
+			}
+			h.Add(k, sample)
+		}
+	}
+	maps.Copy(db.Fingerprints, data.Fingerprints)
+	return db, nil
+}
+
+// Save writes the database to path atomically
+func Save(path string, db *DB) error {
+	data := storeFile{
+		Version:      storeVersion,
+		Tests:        make(map[string][]storedSample, len(db.Tests)),
+		Fingerprints: db.Fingerprints,
+	}
+	for k, samples := range db.Tests {
+		stored := make([]storedSample, 0, len(samples))
+		for _, s := range samples {
+			ss := storedSample{
+				Duration: int64(s.Duration),
+				Parallel: s.Parallel,
+				Outcome:  s.Outcome,
+				Rerun:    s.Rerun,
+				ID:       s.ID,
+			}
+			if !s.Time.IsZero() {
+				ss.Time = s.Time.UnixNano()
+			}
+			stored = append(stored, ss)
+		}
+		data.Tests[k] = stored
+	}
+
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("failed to create directory for timing database: %w", err)
+		}
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to create timing database: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to create timing database: %w", err)
+	}
+
+	zw := gzip.NewWriter(tmp)
+	if err := json.NewEncoder(zw).Encode(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to encode timing database: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write timing database: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write timing database: %w", err)
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,98 @@
+// Package hygiene compares the timing history with the tests found in the source.
+package hygiene
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/takuo/go-testsplitter/internal/history"
+)
+
+// Report lists the differences between the history and the current tests
+type Report struct {
+	Orphaned []string          // history keys of tests that no longer exist
+	Missing  []string          // keys of current tests without history
+	Renamed  map[string]string // history key of a renamed test -> its current key
+}
+
+// Check compares the top-level tests in the history with the current tests.
+// current maps every checked package to the fingerprints of its test functions;
+// history keys of other packages are ignored. recorded holds the fingerprints
+// saved with the history, keyed like the history.
+//
+// An orphaned test and a missing test of the same package are considered a
+// rename when they are the only ones sharing the same fingerprint.
+func Check(hist history.History, recorded map[string]string, current map[string]map[string]string) *Report {
+	r := &Report{Renamed: make(map[string]string)}
+	for key := range hist {
+		pkg, fn := history.SplitKey(key)
+		tests, ok := current[pkg]
+		if !ok || history.IsPackageKey(key) || strings.Contains(fn, "/") {
+			continue
+		}
+		if _, ok := tests[fn]; !ok {
+			r.Orphaned = append(r.Orphaned, key)
+		}
+	}
+	for pkg, tests := range current {
+		for fn := range tests {
+			if key := history.Key(pkg, fn); len(hist[key]) == 0 {
+				r.Missing = append(r.Missing, key)
+			}
+		}
+	}
+
+	// group both sides by package and fingerprint
+	type group struct{ orphaned, missing []string }
+	groups := make(map[string]*group)
+	groupOf := func(key, fp string) *group {
+		pkg, _ := history.SplitKey(key)
+		g, ok := groups[pkg+":"+fp]
+		if !ok {
+			g = &group{}
+			groups[pkg+":"+fp] = g
+		}
+		return g
+	}
+	for _, key := range r.Orphaned {
+		if fp := recorded[key]; fp != "" {
+			g := groupOf(key, fp)
+			g.orphaned = append(g.orphaned, key)
+		}
+	}
+	for _, key := range r.Missing {
+		pkg, fn := history.SplitKey(key)
+		if fp := current[pkg][fn]; fp != "" {
+			g := groupOf(key, fp)
+			g.missing = append(g.missing, key)
+		}
+	}
+	for _, g := range groups {
+		if len(g.orphaned) == 1 && len(g.missing) == 1 {
+			r.Renamed[g.orphaned[0]] = g.missing[0]
+		}
+	}
+
+	renamedTo := make(map[string]bool, len(r.Renamed))
+	for _, to := range r.Renamed {
+		renamedTo[to] = true
+	}
+	r.Orphaned = slices.DeleteFunc(r.Orphaned, func(key string) bool { return r.Renamed[key] != "" })
+	r.Missing = slices.DeleteFunc(r.Missing, func(key string) bool { return renamedTo[key] })
+	slices.Sort(r.Orphaned)
+	slices.Sort(r.Missing)
+	return r
+}
+
+// Apply carries the history of the renamed tests over to their current keys
+// and removes the orphaned tests with their subtests. It returns the number of
+// removed keys.
+func (r *Report) Apply(hist history.History) (removed int) {
+	for from, to := range r.Renamed {
+		hist.Rename(from, to)
+	}
+	for _, key := range r.Orphaned {
+		removed += hist.Remove(key)
+	}
+	return removed
+}
@@ -0,0 +1,53 @@
+package hygiene
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/takuo/go-testsplitter/internal/history"
+)
+
+func TestCheck(t *testing.T) {
+	sample := []history.Sample{{Duration: time.Second}}
+	hist := history.History{
+		"pkg1:":              sample,
+		"pkg1:TestKept":      sample,
+		"pkg1:TestOld":       sample,
+		"pkg1:TestOld/sub":   sample,
+		"pkg1:TestDeleted":   sample,
+		"pkg1:TestTwinA":     sample,
+		"pkg1:TestTwinB":     sample,
+		"pkg2:TestElsewhere": sample,
+	}
+	recorded := map[string]string{
+		"pkg1:TestKept":    "aaaa",
+		"pkg1:TestOld":     "bbbb",
+		"pkg1:TestDeleted": "cccc",
+		"pkg1:TestTwinA":   "dddd",
+		"pkg1:TestTwinB":   "dddd",
+	}
+	current := map[string]map[string]string{
+		"pkg1": {
+			"TestKept": "aaaa",
+			"TestNew":  "bbbb",
+			"TestAdd":  "eeee",
+			"TestTwin": "dddd",
+		},
+	}
+
+	r := Check(hist, recorded, current)
+	assert.Equal(t, map[string]string{"pkg1:TestOld": "pkg1:TestNew"}, r.Renamed)
+	assert.Equal(t, []string{"pkg1:TestDeleted", "pkg1:TestTwinA", "pkg1:TestTwinB"}, r.Orphaned, "ambiguous fingerprints are not renames")
+	assert.Equal(t, []string{"pkg1:TestAdd", "pkg1:TestTwin"}, r.Missing)
+
+	assert.Equal(t, 3, r.Apply(hist))
+	assert.Equal(t, history.History{
+		"pkg1:":              sample,
+		"pkg1:TestKept":      sample,
+		"pkg1:TestNew":       sample,
+		"pkg1:TestNew/sub":   sample,
+		"pkg2:TestElsewhere": sample,
+	}, hist)
+}
@@ -0,0 +1,168 @@
+// Package labels matches tests against label expressions like "e2e and not slow".
+package labels
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+)
+
+// Expr is a boolean expression of labels
+type Expr interface {
+	// Match reports whether a test with the given labels satisfies the expression
+	Match(labels []string) bool
+}
+
+type (
+	label string
+	not   struct{ x Expr }
+	and   struct{ x, y Expr }
+	or    struct{ x, y Expr }
+)
+
+func (e label) Match(labels []string) bool { return slices.Contains(labels, string(e)) }
+func (e not) Match(labels []string) bool   { return !e.x.Match(labels) }
+func (e and) Match(labels []string) bool   { return e.x.Match(labels) && e.y.Match(labels) }
+func (e or) Match(labels []string) bool    { return e.x.Match(labels) || e.y.Match(labels) }
+
+// Parse parses an expression of labels combined with "and", "or", "not" and
+// parentheses, e.g. "(e2e or smoke) and not slow". "and" binds tighter than "or".
+func Parse(s string) (Expr, error) {
+	p := &exprParser{tokens: tokenize(s)}
+	if len(p.tokens) == 0 {
+		return nil, fmt.Errorf("empty label expression")
+	}
+	e, err := p.or()
+	if err != nil {
+		return nil, fmt.Errorf("invalid label expression %q: %w", s, err)
+	}
+	if tok, ok := p.peek(); ok {
+		return nil, fmt.Errorf("invalid label expression %q: unexpected %q", s, tok)
+	}
+	return e, nil
+}
+
+// tokenize splits the expression into parentheses and words
+func tokenize(s string) []string {
+	var tokens []string
+	for _, field := range strings.Fields(s) {
+		for field != "" {
+			i := strings.IndexAny(field, "()")
+			switch {
+			case i < 0:
+				tokens, field = append(tokens, field), ""
+			case i == 0:
+				tokens, field = append(tokens, field[:1]), field[1:]
+			default:
+				tokens, field = append(tokens, field[:i]), field[i:]
+			}
+		}
+	}
+	return tokens
+}
+
+type exprParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *exprParser) peek() (string, bool) {
+	if p.pos >= len(p.tokens) {
+		return "", false
+	}
+	return p.tokens[p.pos], true
+}
+
+func (p *exprParser) accept(tok string) bool {
+	if t, ok := p.peek(); ok && t == tok {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *exprParser) or() (Expr, error) {
+	x, err := p.and()
+	for err == nil && p.accept("or") {
+		var y Expr
+		if y, err = p.and(); err == nil {
+			x = or{x, y}
+		}
+	}
+	return x, err
+}
+
+func (p *exprParser) and() (Expr, error) {
+	x, err := p.unary()
+	for err == nil && p.accept("and") {
+		var y Expr
+		if y, err = p.unary(); err == nil {
+			x = and{x, y}
+		}
+	}
+	return x, err
+}
+
+func (p *exprParser) unary() (Expr, error) {
+	tok, ok := p.peek()
+	if !ok {
+		return nil, fmt.Errorf("unexpected end")
+	}
+	p.pos++
+	switch tok {
+	case "not":
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return not{x}, nil
+	case "(":
+		x, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(")") {
+			return nil, fmt.Errorf("missing )")
+		}
+		return x, nil
+	case ")", "and", "or":
+		return nil, fmt.Errorf("unexpected %q", tok)
+	}
+	return label(tok), nil
+}
+
+// Rule gives Label to the tests or packages matching Pattern
+type Rule struct {
+	Label   string
+	Pattern string
+}
+
+// ParseRule parses a rule given as LABEL=PATTERN
+func ParseRule(s string) (Rule, error) {
+	name, pattern, ok := strings.Cut(s, "=")
+	if !ok || name == "" || pattern == "" {
+		return Rule{}, fmt.Errorf("invalid label rule %q: expected LABEL=PATTERN", s)
+	}
+	if _, err := path.Match(strings.TrimSuffix(pattern, "/..."), ""); err != nil {
+		return Rule{}, fmt.Errorf("invalid label rule %q: %w", s, err)
+	}
+	return Rule{Label: name, Pattern: pattern}, nil
+}
+
+// Match reports whether name matches the glob pattern of the rule.
+// A pattern ending with "/..." also matches every path below the matching ones,
+// like the package patterns of the go command.
+func (r Rule) Match(name string) bool {
+	prefix, ok := strings.CutSuffix(r.Pattern, "/...")
+	if !ok {
+		matched, _ := path.Match(r.Pattern, name)
+		return matched
+	}
+	for p := name; p != "." && p != "/" && p != ""; p = path.Dir(p) {
+		if matched, _ := path.Match(prefix, p); matched {
+			return true
+		}
+	}
+	return false
+}
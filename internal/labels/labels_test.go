@@ -0,0 +1,57 @@
+package labels
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		expr   string
+		labels []string
+		want   bool
+	}{
+		{"slow", []string{"slow"}, true},
+		{"slow", []string{"e2e"}, false},
+		{"not slow", []string{"e2e"}, true},
+		{"e2e or smoke", []string{"smoke"}, true},
+		{"e2e and smoke", []string{"smoke"}, false},
+		{"e2e or smoke and slow", []string{"e2e"}, true},
+		{"(e2e or smoke) and slow", []string{"e2e"}, false},
+		{"(e2e or smoke) and not slow", []string{"smoke", "db"}, true},
+		{"not (e2e)", nil, true},
+		{"not not e2e", []string{"e2e"}, true},
+	}
+	for _, tt := range tests {
+		e, err := Parse(tt.expr)
+		require.NoError(t, err, tt.expr)
+		assert.Equal(t, tt.want, e.Match(tt.labels), "%s with %v", tt.expr, tt.labels)
+	}
+
+	for _, expr := range []string{"", "e2e and", "(e2e", "e2e)", "or e2e", "e2e smoke", "not"} {
+		_, err := Parse(expr)
+		assert.Error(t, err, expr)
+	}
+}
+
+func TestRule(t *testing.T) {
+	rule, err := ParseRule("e2e=test/e2e/...")
+	require.NoError(t, err)
+	assert.Equal(t, Rule{Label: "e2e", Pattern: "test/e2e/..."}, rule)
+	assert.True(t, rule.Match("test/e2e"))
+	assert.True(t, rule.Match("test/e2e/api"))
+	assert.False(t, rule.Match("test/e2eutil"))
+	assert.False(t, rule.Match("test"))
+
+	rule, err = ParseRule("slow=*Slow*")
+	require.NoError(t, err)
+	assert.True(t, rule.Match("TestSlowQuery"))
+	assert.False(t, rule.Match("TestQuery"))
+
+	for _, s := range []string{"slow", "=*Slow*", "slow=", "bad=[x"} {
+		_, err := ParseRule(s)
+		assert.Error(t, err, s)
+	}
+}
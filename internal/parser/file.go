@@ -0,0 +1,105 @@
+package parser
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/takuo/go-testsplitter/internal/ginkgo"
+	"github.com/takuo/go-testsplitter/internal/history"
+)
+
+// Supported reports whether a file name is a result file read by ParseFile:
+// go test -json or Ginkgo JSON report (.jsonl, .json), JUnit XML (.xml), any of them compressed
+// with gzip (.gz), or a tar archive of them (.tar, .tar.gz, .tgz).
+func Supported(name string) bool {
+	if isTar(name) {
+		return true
+	}
+	switch path.Ext(strings.TrimSuffix(name, ".gz")) {
+	case ".jsonl", ".json", ".xml":
+		return true
+	}
+	return false
+}
+
+func isTar(name string) bool {
+	return strings.HasSuffix(name, ".tar") || strings.HasSuffix(name, ".tar.gz") || strings.HasSuffix(name, ".tgz")
+}
+
+// ParseFile parses a result file read from r, choosing the format by its name.
+// Compressed files and archive entries are read as streams.
+func ParseFile(name string, r io.Reader) (history.History, error) {
+	if base, ok := strings.CutSuffix(name, ".tgz"); ok {
+		name = base + ".tar.gz"
+	}
+	if base, ok := strings.CutSuffix(name, ".gz"); ok {
+		zr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		defer zr.Close()
+		return ParseFile(base, zr)
+	}
+
+	switch {
+	case strings.HasSuffix(name, ".tar"):
+		return parseTar(r)
+	case path.Ext(name) == ".xml":
+		return ParseJUnitXML(r)
+	}
+	br := bufio.NewReader(r)
+	if isJSONArray(br) {
+		return ginkgo.ParseReport(br)
+	}
+	return ParseGoTestJSONL(bufio.NewScanner(br)), nil
+}
+
+// isJSONArray reports whether the content starts with a JSON array,
+// as a Ginkgo JSON report does, rather than with go test -json events
+func isJSONArray(br *bufio.Reader) bool {
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			return false
+		}
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		_ = br.UnreadByte()
+		return b == '['
+	}
+}
+
+// parseTar parses every supported result file in a tar archive.
+// An entry that fails to parse is skipped with its error returned along the results.
+func parseTar(r io.Reader) (history.History, error) {
+	results := make(history.History)
+	var errs []error
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return results, errors.Join(append(errs, fmt.Errorf("failed to read archive: %w", err))...)
+		}
+		if hdr.Typeflag != tar.TypeReg || !Supported(hdr.Name) || isTar(hdr.Name) {
+			continue
+		}
+		data, err := ParseFile(hdr.Name, tr)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", hdr.Name, err))
+			continue
+		}
+		results.Merge(data)
+	}
+	return results, errors.Join(errs...)
+}
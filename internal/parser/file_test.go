@@ -0,0 +1,73 @@
+package parser
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	fileJSONL = `{"Time":"2025-01-01T00:00:00Z","Action":"run","Package":"pkg","Test":"TestA"}
+{"Time":"2025-01-01T00:00:02Z","Action":"pass","Package":"pkg","Test":"TestA"}
+`
+	fileXML = `<testsuite name="pkg" time="1"><testcase classname="pkg" name="TestB" time="1"></testcase></testsuite>`
+)
+
+func gzipped(t *testing.T, data []byte) []byte {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, err := zw.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestParseFile(t *testing.T) {
+	var archive bytes.Buffer
+	tw := tar.NewWriter(&archive)
+	for _, entry := range []struct {
+		name string
+		data []byte
+	}{
+		{"test-json/test-0-0.jsonl", []byte(fileJSONL)},
+		{"test-reports/junit-0-0.xml.gz", gzipped(t, []byte(fileXML))},
+		{"README.md", []byte("not a result")},
+	} {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: entry.name, Mode: 0o644, Size: int64(len(entry.data)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write(entry.data)
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+
+	for name, data := range map[string][]byte{
+		"test.jsonl.gz":    gzipped(t, []byte(fileJSONL)),
+		"results.tar":      archive.Bytes(),
+		"results.tar.gz":   gzipped(t, archive.Bytes()),
+		"results.tgz":      gzipped(t, archive.Bytes()),
+		"junit-0-0.xml":    []byte(fileXML),
+		"test-0-0.json":    []byte(fileJSONL),
+		"junit-0-0.xml.gz": gzipped(t, []byte(fileXML)),
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.True(t, Supported(name))
+			h, err := ParseFile(name, bytes.NewReader(data))
+			require.NoError(t, err)
+			assert.NotEmpty(t, h)
+			for key := range h {
+				assert.Contains(t, []string{"pkg:TestA", "pkg:TestB", "pkg:"}, key)
+			}
+		})
+	}
+
+	h, err := ParseFile("results.tgz", bytes.NewReader(gzipped(t, archive.Bytes())))
+	require.NoError(t, err)
+	assert.Len(t, h["pkg:TestA"], 1, "go test -json entry")
+	assert.Len(t, h["pkg:TestB"], 1, "gzip-compressed JUnit XML entry")
+
+	assert.False(t, Supported("README.md"))
+	assert.False(t, Supported("data.gz"))
+}
@@ -5,25 +5,66 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"slices"
 	"strings"
 	"time"
+
+	"github.com/takuo/go-testsplitter/internal/history"
 )
 
 type testEvent struct {
-	Time    string `json:"Time"`
-	Action  string `json:"Action"`
-	Package string `json:"Package"`
-	Test    string `json:"Test"`
+	Time    string   `json:"Time"`
+	Action  string   `json:"Action"`
+	Package string   `json:"Package"`
+	Test    string   `json:"Test"`
+	Elapsed *float64 `json:"Elapsed"`
 }
 
-type testRecord struct {
-	Start time.Time
-	End   time.Time
+// duration returns the elapsed time reported by test2json, falling back to
+// the difference between the start and the terminal event timestamps.
+// The timestamps include time spent paused by t.Parallel(), so Elapsed is preferred.
+func (ev *testEvent) duration(start, end time.Time) (time.Duration, bool) {
+	if ev.Elapsed != nil {
+		return time.Duration(*ev.Elapsed * float64(time.Second)), true
+	}
+	if start.IsZero() || end.IsZero() {
+		return 0, false
+	}
+	return end.Sub(start), true
 }
 
-// ParseGoTestJSONL parse `go test -json` output (JSON Lines) from a bufio.Scanner
-func ParseGoTestJSONL(scanner *bufio.Scanner) map[string]time.Duration {
-	records := make(map[string]*testRecord)
+// interval is the wall time a test run covers
+type interval struct{ start, end time.Time }
+
+// coveredTime returns the length of the union of the intervals, so tests
+// running in parallel are not counted twice
+func coveredTime(intervals []interval) (total time.Duration) {
+	slices.SortFunc(intervals, func(a, b interval) int { return a.start.Compare(b.start) })
+	var cur interval
+	for _, iv := range intervals {
+		if cur.end.IsZero() || iv.start.After(cur.end) {
+			total += cur.end.Sub(cur.start)
+			cur = iv
+		} else if iv.end.After(cur.end) {
+			cur.end = iv.end
+		}
+	}
+	return total + cur.end.Sub(cur.start)
+}
+
+// ParseGoTestJSONL parse `go test -json` output (JSON Lines) from a bufio.Scanner.
+// Every run of a test (e.g. with -count=N or reruns) is kept as a separate sample.
+// Subtests are recorded under their full name, e.g. "TestX/case_a".
+// The time a package run takes beyond the wall time covered by its tests
+// (binary start-up, TestMain) is recorded as the package overhead under history.PackageKey.
+func ParseGoTestJSONL(scanner *bufio.Scanner) history.History {
+	starts := make(map[string]time.Time)
+	paused := make(map[string]bool)
+	failed := make(map[string]bool)
+	// per package, since the last package result
+	testsCovered := make(map[string][]interval)
+	testsUntimed := make(map[string]time.Duration) // tests without timestamps
+	results := make(history.History)
 	for scanner.Scan() {
 		var ev testEvent
 		if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
@@ -31,28 +72,60 @@ func ParseGoTestJSONL(scanner *bufio.Scanner) map[string]time.Duration {
 			continue
 		}
 
-		if ev.Test == "" || strings.Contains(ev.Test, "/") {
-			// ignore subtest
+		if ev.Test == "" {
+			key := history.PackageKey(ev.Package)
+			switch ev.Action {
+			case "start":
+				starts[key], _ = time.Parse(time.RFC3339Nano, ev.Time)
+			case "pass", "fail":
+				start := starts[key]
+				delete(starts, key)
+				end, _ := time.Parse(time.RFC3339Nano, ev.Time)
+				total := coveredTime(testsCovered[ev.Package]) + testsUntimed[ev.Package]
+				delete(testsCovered, ev.Package)
+				delete(testsUntimed, ev.Package)
+				if d, ok := ev.duration(start, end); ok && total > 0 && d > total {
+					results.Add(key, history.Sample{Duration: d - total, Time: end})
+				}
+			}
 			continue
 		}
-		key := fmt.Sprintf("%s:%s", ev.Package, ev.Test)
-		rec, ok := records[key]
-		if !ok {
-			rec = &testRecord{}
-			records[key] = rec
-		}
+		key := history.Key(ev.Package, ev.Test)
 
 		switch ev.Action {
 		case "run":
-			rec.Start, _ = time.Parse(time.RFC3339, ev.Time)
+			starts[key], _ = time.Parse(time.RFC3339Nano, ev.Time)
+		case "pause":
+			paused[key] = true
 		case "pass", "fail", "skip":
-			rec.End, _ = time.Parse(time.RFC3339, ev.Time)
-		}
-	}
-	results := make(map[string]time.Duration, len(records))
-	for k, v := range records {
-		if !v.Start.IsZero() && !v.End.IsZero() {
-			results[k] = v.End.Sub(v.Start)
+			start := starts[key]
+			parallel := paused[key]
+			delete(starts, key)
+			delete(paused, key)
+			end, _ := time.Parse(time.RFC3339Nano, ev.Time)
+			rerun := failed[key]
+			if ev.Action == history.OutcomeFail {
+				failed[key] = true
+			}
+			if d, ok := ev.duration(start, end); ok {
+				results.Add(key, history.Sample{
+					Duration: d,
+					Time:     end,
+					Parallel: parallel,
+					Outcome:  ev.Action,
+					Rerun:    rerun,
+				})
+				switch {
+				case strings.Contains(ev.Test, "/"):
+					// subtests run within their parent
+				case end.IsZero():
+					testsUntimed[ev.Package] += d
+				case start.IsZero():
+					testsCovered[ev.Package] = append(testsCovered[ev.Package], interval{end.Add(-d), end})
+				default:
+					testsCovered[ev.Package] = append(testsCovered[ev.Package], interval{start, end})
+				}
+			}
 		}
 	}
 	return results
@@ -0,0 +1,90 @@
+package parser
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseGoTestJSONL_Repeated(t *testing.T) {
+	input := `{"Time":"2025-01-01T00:00:00Z","Action":"run","Package":"pkg","Test":"TestA"}
+{"Time":"2025-01-01T00:00:02Z","Action":"fail","Package":"pkg","Test":"TestA"}
+{"Time":"2025-01-01T00:00:02Z","Action":"run","Package":"pkg","Test":"TestA/sub"}
+{"Time":"2025-01-01T00:00:03Z","Action":"pass","Package":"pkg","Test":"TestA/sub"}
+{"Time":"2025-01-01T00:00:10Z","Action":"run","Package":"pkg","Test":"TestA"}
+{"Time":"2025-01-01T00:00:13Z","Action":"pass","Package":"pkg","Test":"TestA"}
+`
+	h := ParseGoTestJSONL(bufio.NewScanner(strings.NewReader(input)))
+
+	assert.Len(t, h, 2)
+	assert.Len(t, h["pkg:TestA/sub"], 1, "subtests should be recorded")
+	samples := h["pkg:TestA"]
+	if assert.Len(t, samples, 2, "every run should be kept") {
+		assert.Equal(t, 2*time.Second, samples[0].Duration)
+		assert.Equal(t, 3*time.Second, samples[1].Duration)
+		assert.Equal(t, "fail", samples[0].Outcome)
+		assert.False(t, samples[0].Rerun)
+		assert.Equal(t, "pass", samples[1].Outcome)
+		assert.True(t, samples[1].Rerun, "pass after fail in the same run")
+		assert.Equal(t, time.Date(2025, 1, 1, 0, 0, 13, 0, time.UTC), samples[1].Time)
+	}
+}
+
+func TestParseGoTestJSONL_Elapsed(t *testing.T) {
+	input := `{"Time":"2025-01-01T00:00:00.000000001Z","Action":"run","Package":"pkg","Test":"TestParallel"}
+{"Time":"2025-01-01T00:00:00.5Z","Action":"pause","Package":"pkg","Test":"TestParallel"}
+{"Time":"2025-01-01T00:00:09Z","Action":"cont","Package":"pkg","Test":"TestParallel"}
+{"Time":"2025-01-01T00:00:10.250000001Z","Action":"pass","Package":"pkg","Test":"TestParallel","Elapsed":1.25}
+{"Time":"2025-01-01T00:00:10Z","Action":"run","Package":"pkg","Test":"TestNoElapsed"}
+{"Time":"2025-01-01T00:00:10.75Z","Action":"pass","Package":"pkg","Test":"TestNoElapsed"}
+`
+	h := ParseGoTestJSONL(bufio.NewScanner(strings.NewReader(input)))
+
+	if assert.Len(t, h["pkg:TestParallel"], 1) {
+		s := h["pkg:TestParallel"][0]
+		assert.Equal(t, 1250*time.Millisecond, s.Duration, "Elapsed should be preferred")
+		assert.True(t, s.Parallel, "paused test should be parallel")
+		assert.Equal(t, 250000001, s.Time.Nanosecond(), "Time should keep nanoseconds")
+	}
+	if assert.Len(t, h["pkg:TestNoElapsed"], 1) {
+		assert.Equal(t, 750*time.Millisecond, h["pkg:TestNoElapsed"][0].Duration, "timestamps are the fallback")
+		assert.False(t, h["pkg:TestNoElapsed"][0].Parallel)
+	}
+}
+
+func TestParseGoTestJSONL_PackageOverhead(t *testing.T) {
+	input := `{"Time":"2025-01-01T00:00:00Z","Action":"start","Package":"pkg"}
+{"Time":"2025-01-01T00:00:02Z","Action":"run","Package":"pkg","Test":"TestA"}
+{"Time":"2025-01-01T00:00:03Z","Action":"pass","Package":"pkg","Test":"TestA","Elapsed":1}
+{"Time":"2025-01-01T00:00:03Z","Action":"run","Package":"pkg","Test":"TestB"}
+{"Time":"2025-01-01T00:00:03.5Z","Action":"pass","Package":"pkg","Test":"TestB","Elapsed":0.5}
+{"Time":"2025-01-01T00:00:04Z","Action":"pass","Package":"pkg","Elapsed":4}
+{"Time":"2025-01-01T00:00:04Z","Action":"start","Package":"fast"}
+{"Time":"2025-01-01T00:00:04Z","Action":"run","Package":"fast","Test":"TestC"}
+{"Time":"2025-01-01T00:00:05Z","Action":"pass","Package":"fast","Test":"TestC","Elapsed":1}
+{"Time":"2025-01-01T00:00:05Z","Action":"pass","Package":"fast","Elapsed":1}
+{"Time":"2025-01-01T00:00:05Z","Action":"start","Package":"parallel"}
+{"Time":"2025-01-01T00:00:08Z","Action":"run","Package":"parallel","Test":"TestP1"}
+{"Time":"2025-01-01T00:00:08Z","Action":"pause","Package":"parallel","Test":"TestP1"}
+{"Time":"2025-01-01T00:00:08Z","Action":"run","Package":"parallel","Test":"TestP2"}
+{"Time":"2025-01-01T00:00:08Z","Action":"pause","Package":"parallel","Test":"TestP2"}
+{"Time":"2025-01-01T00:00:08Z","Action":"cont","Package":"parallel","Test":"TestP1"}
+{"Time":"2025-01-01T00:00:08Z","Action":"cont","Package":"parallel","Test":"TestP2"}
+{"Time":"2025-01-01T00:00:10Z","Action":"pass","Package":"parallel","Test":"TestP1","Elapsed":2}
+{"Time":"2025-01-01T00:00:10Z","Action":"pass","Package":"parallel","Test":"TestP2","Elapsed":2}
+{"Time":"2025-01-01T00:00:10Z","Action":"pass","Package":"parallel","Elapsed":3.5}
+`
+	h := ParseGoTestJSONL(bufio.NewScanner(strings.NewReader(input)))
+
+	if assert.Len(t, h["pkg:"], 1) {
+		assert.Equal(t, 2500*time.Millisecond, h["pkg:"][0].Duration)
+	}
+	assert.NotContains(t, h, "fast:", "no overhead beyond the tests")
+	// the parallel tests cover 2s of wall time, though their durations sum up to 4s
+	if assert.Len(t, h["parallel:"], 1) {
+		assert.Equal(t, 1500*time.Millisecond, h["parallel:"][0].Duration)
+	}
+}
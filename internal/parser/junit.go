@@ -0,0 +1,86 @@
+package parser
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/takuo/go-testsplitter/internal/history"
+	"github.com/takuo/go-testsplitter/internal/types"
+)
+
+// ParseJUnitXML parse a JUnit XML report (e.g. written by gotestsum --junitfile).
+// The root element may be either <testsuites> or a single <testsuite>.
+// The classname of a test case is used as the package, falling back to the suite name.
+// Every sample gets an ID derived from the content of the report, as JUnit has
+// no times per test case, so ingesting the same report again adds nothing.
+func ParseJUnitXML(r io.Reader) (history.History, error) {
+	results := make(history.History)
+	h := sha256.New()
+	dec := xml.NewDecoder(io.TeeReader(r, h))
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			digest := hex.EncodeToString(h.Sum(nil)[:8])
+			for _, samples := range results {
+				for i := range samples {
+					samples[i].ID = fmt.Sprintf("junit:%s:%d", digest, i)
+				}
+			}
+			return results, nil
+		}
+		if err != nil {
+			return results, fmt.Errorf("failed to parse JUnit XML: %w", err)
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "testsuite" {
+			continue
+		}
+		var suite types.TestSuite
+		if err := dec.DecodeElement(&suite, &start); err != nil {
+			return results, fmt.Errorf("failed to parse JUnit test suite: %w", err)
+		}
+		addTestSuite(results, &suite)
+	}
+}
+
+func addTestSuite(results history.History, suite *types.TestSuite) {
+	// JUnit only records when the suite started, so all cases share that time
+	timestamp, _ := time.Parse(time.RFC3339Nano, suite.Timestamp)
+	var total time.Duration
+	failed := make(map[string]bool)
+	for _, tc := range suite.TestCases {
+		if tc.Name == "" {
+			continue
+		}
+		pkg := tc.Classname
+		if pkg == "" {
+			pkg = suite.Name
+		}
+		d := time.Duration(tc.Time * float64(time.Second))
+		key := history.Key(pkg, tc.Name)
+		outcome := history.OutcomePass
+		switch {
+		case tc.Failure != nil, tc.Error != nil:
+			outcome = history.OutcomeFail
+		case tc.Skipped != nil:
+			outcome = history.OutcomeSkip
+		}
+		results.Add(key, history.Sample{Duration: d, Time: timestamp, Outcome: outcome, Rerun: failed[key]})
+		if outcome == history.OutcomeFail {
+			failed[key] = true
+		}
+		if !strings.Contains(tc.Name, "/") {
+			total += d
+		}
+	}
+	// the suite time of go test reports is the package elapsed time
+	if elapsed := time.Duration(suite.Time * float64(time.Second)); suite.Name != "" && total > 0 && elapsed > total {
+		results.Add(history.PackageKey(suite.Name), history.Sample{Duration: elapsed - total, Time: timestamp})
+	}
+}
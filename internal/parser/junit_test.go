@@ -0,0 +1,75 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseJUnitXML(t *testing.T) {
+	input := `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites tests="4" failures="1" errors="0" time="3.5">
+	<testsuite tests="3" failures="1" time="3.0" name="example/pkg1" timestamp="2025-01-01T00:00:00Z">
+		<testcase classname="example/pkg1" name="TestAdd" time="1.5"></testcase>
+		<testcase classname="example/pkg1" name="TestAdd/sub" time="1.0"></testcase>
+		<testcase classname="example/pkg1" name="TestFlaky" time="0.25">
+			<failure message="Failed" type="">boom</failure>
+		</testcase>
+		<testcase classname="example/pkg1" name="TestFlaky" time="0.5"></testcase>
+	</testsuite>
+	<testsuite tests="1" time="0.5" name="example/pkg2">
+		<testcase name="TestReverse" time="0.5"></testcase>
+	</testsuite>
+</testsuites>`
+
+	h, err := ParseJUnitXML(strings.NewReader(input))
+	require.NoError(t, err)
+	assert.Len(t, h, 5)
+	assert.Len(t, h["example/pkg1:TestAdd/sub"], 1, "subtests should be recorded")
+
+	if assert.Len(t, h["example/pkg1:TestAdd"], 1) {
+		s := h["example/pkg1:TestAdd"][0]
+		assert.Equal(t, 1500*time.Millisecond, s.Duration)
+		assert.Equal(t, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), s.Time)
+	}
+	if assert.Len(t, h["example/pkg1:TestFlaky"], 2, "reruns should be kept") {
+		assert.Equal(t, "fail", h["example/pkg1:TestFlaky"][0].Outcome)
+		assert.Equal(t, "pass", h["example/pkg1:TestFlaky"][1].Outcome)
+		assert.True(t, h["example/pkg1:TestFlaky"][1].Rerun)
+	}
+	assert.Len(t, h["example/pkg2:TestReverse"], 1, "suite name is used without classname")
+	if assert.Len(t, h["example/pkg1:"], 1, "package overhead") {
+		assert.Equal(t, 750*time.Millisecond, h["example/pkg1:"][0].Duration)
+	}
+}
+
+func TestParseJUnitXML_SingleSuite(t *testing.T) {
+	input := `<testsuite name="pkg"><testcase classname="pkg" name="TestA" time="2"/></testsuite>`
+
+	h, err := ParseJUnitXML(strings.NewReader(input))
+	require.NoError(t, err)
+	assert.Equal(t, 2*time.Second, h["pkg:TestA"][0].Duration)
+}
+
+func TestParseJUnitXML_Identity(t *testing.T) {
+	input := `<testsuite name="pkg"><testcase classname="pkg" name="TestA" time="2"/><testcase classname="pkg" name="TestA" time="2"/></testsuite>`
+
+	h, err := ParseJUnitXML(strings.NewReader(input))
+	require.NoError(t, err)
+	require.Len(t, h["pkg:TestA"], 2)
+	assert.NotEqual(t, h["pkg:TestA"][0].ID, h["pkg:TestA"][1].ID, "runs of a report are distinct")
+
+	// the same report without a timestamp is counted once
+	again, err := ParseJUnitXML(strings.NewReader(input))
+	require.NoError(t, err)
+	h.Merge(again)
+	assert.Len(t, h["pkg:TestA"], 2)
+
+	other, err := ParseJUnitXML(strings.NewReader(strings.Replace(input, `time="2"`, `time="3"`, 1)))
+	require.NoError(t, err)
+	h.Merge(other)
+	assert.Len(t, h["pkg:TestA"], 4, "another report adds its samples")
+}
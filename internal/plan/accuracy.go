@@ -0,0 +1,212 @@
+package plan
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/takuo/go-testsplitter/internal/costmodel"
+	"github.com/takuo/go-testsplitter/internal/history"
+)
+
+// NodeAccuracy compares the predicted and the actual duration of a node
+type NodeAccuracy struct {
+	Index     int
+	Predicted time.Duration
+	// Actual is the cost model of the split applied to the actual durations,
+	// with overlapping t.Parallel() tests, comparable to the prediction
+	Actual  time.Duration
+	Sum     time.Duration // plain sum of the test durations and package overheads
+	Wall    time.Duration // from the start of the first test to the end of the last one
+	Tests   int           // planned tests
+	Missing int           // planned tests without results
+}
+
+// RelError returns the relative error of the prediction, positive when the node took longer
+func (n NodeAccuracy) RelError() float64 {
+	return relError(n.Predicted, n.Actual)
+}
+
+// TestAccuracy compares the predicted and the actual duration of a test on a node.
+// The parts of a test split by subtests are added up.
+type TestAccuracy struct {
+	Node      int
+	Package   string
+	Function  string
+	Predicted time.Duration
+	Actual    time.Duration // including reruns
+}
+
+// RelError returns the relative error of the prediction, positive when the test took longer
+func (t TestAccuracy) RelError() float64 {
+	return relError(t.Predicted, t.Actual)
+}
+
+func relError(predicted, actual time.Duration) float64 {
+	if predicted == 0 {
+		return 0
+	}
+	return float64(actual-predicted) / float64(predicted)
+}
+
+// Accuracy is the result of comparing a plan with the actual results
+type Accuracy struct {
+	Nodes []NodeAccuracy
+	Tests []TestAccuracy // largest absolute error first
+}
+
+// Compare compares the plan with the results of each node keyed by node index.
+// Samples that finished before the plan was created are ignored.
+func Compare(p *Plan, results map[int]history.History) *Accuracy {
+	a := &Accuracy{}
+	for _, node := range p.Nodes {
+		actual := actualDurations(results[node.Index], p.Created)
+		na := NodeAccuracy{
+			Index:     node.Index,
+			Predicted: seconds(node.Predicted),
+		}
+		var first, last time.Time
+		for key, samples := range actual {
+			_, fn := history.SplitKey(key)
+			if strings.Contains(fn, "/") {
+				continue
+			}
+			for _, s := range samples {
+				na.Sum += s.Duration
+				if s.Time.IsZero() || history.IsPackageKey(key) {
+					continue
+				}
+				if start := s.Time.Add(-s.Duration); first.IsZero() || start.Before(first) {
+					first = start
+				}
+				if s.Time.After(last) {
+					last = s.Time
+				}
+			}
+		}
+		na.Wall = last.Sub(first)
+
+		var tests []TestAccuracy
+		for _, t := range node.Tests {
+			i := slices.IndexFunc(tests, func(ta TestAccuracy) bool {
+				return ta.Package == t.Package && ta.Function == t.Function
+			})
+			if i < 0 {
+				tests = append(tests, TestAccuracy{Node: node.Index, Package: t.Package, Function: t.Function})
+				i = len(tests) - 1
+			}
+			tests[i].Predicted += seconds(t.Predicted)
+		}
+		for _, ta := range tests {
+			na.Tests++
+			samples := actual[history.Key(ta.Package, ta.Function)]
+			if len(samples) == 0 {
+				na.Missing++
+				continue
+			}
+			for _, s := range samples {
+				ta.Actual += s.Duration
+			}
+			a.Tests = append(a.Tests, ta)
+		}
+		na.Actual = modeledCost(p, node, actual)
+		a.Nodes = append(a.Nodes, na)
+	}
+	slices.SortStableFunc(a.Tests, func(x, y TestAccuracy) int {
+		return cmp.Compare(absDiff(y.Actual, y.Predicted), absDiff(x.Actual, x.Predicted))
+	})
+	return a
+}
+
+// modeledCost applies the cost model of the split to the actual durations of
+// the tests of the node: the package overhead is charged per invocation, and
+// the t.Parallel() tests of an invocation overlap
+func modeledCost(p *Plan, node Node, actual history.History) time.Duration {
+	m := &costmodel.Model{
+		Durations:    make(map[string]time.Duration),
+		Overheads:    make(map[string]time.Duration),
+		Standalone:   make(map[string]bool),
+		Parallel:     make(map[string]bool),
+		MaxFunctions: p.MaxFunctions,
+		TestParallel: p.TestParallel,
+	}
+	var pkgs []string
+	byPkg := make(map[string][]string)
+	parts := make(map[string]int)
+	for _, t := range node.Tests {
+		key := history.Key(t.Package, t.Function)
+		parts[key]++
+		if parts[key] > 1 {
+			continue
+		}
+		for _, s := range actual[key] {
+			m.Durations[key] += s.Duration
+			m.Parallel[key] = m.Parallel[key] || s.Parallel
+		}
+		m.Parallel[key] = m.Parallel[key] || t.Parallel
+		m.Standalone[key] = t.Standalone
+		if _, ok := byPkg[t.Package]; !ok {
+			pkgs = append(pkgs, t.Package)
+			if samples := actual[history.PackageKey(t.Package)]; len(samples) > 0 {
+				var sum time.Duration
+				for _, s := range samples {
+					sum += s.Duration
+				}
+				m.Overheads[t.Package] = sum / time.Duration(len(samples))
+			}
+		}
+		byPkg[t.Package] = append(byPkg[t.Package], key)
+	}
+	var total time.Duration
+	for _, pkg := range pkgs {
+		total += m.PackageCost(pkg, byPkg[pkg])
+		// the parts of a test split by subtests are recorded under the test, each paying the overhead
+		for _, key := range byPkg[pkg] {
+			if m.Standalone[key] {
+				total += time.Duration(parts[key]-1) * m.Overheads[pkg]
+			}
+		}
+	}
+	return total
+}
+
+// actualDurations returns the samples finished after the given time
+func actualDurations(h history.History, after time.Time) history.History {
+	actual := make(history.History, len(h))
+	for key, samples := range h {
+		for _, s := range samples {
+			if !s.Time.IsZero() && s.Time.Before(after) {
+				continue
+			}
+			actual.Add(key, s)
+		}
+	}
+	return actual
+}
+
+// Imbalance returns the ratio of the longest duration to the mean, 1 when perfectly balanced
+func Imbalance(durs []time.Duration) float64 {
+	if len(durs) == 0 {
+		return 0
+	}
+	var sum time.Duration
+	for _, d := range durs {
+		sum += d
+	}
+	if sum == 0 {
+		return 0
+	}
+	return float64(slices.Max(durs)) * float64(len(durs)) / float64(sum)
+}
+
+func absDiff(a, b time.Duration) time.Duration {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
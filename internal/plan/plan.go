@@ -0,0 +1,62 @@
+// Package plan records how tests were assigned to nodes and compares it with the actual results.
+package plan
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// FileName is the name of the plan written next to the generated scripts
+const FileName = "plan.json"
+
+// Plan is the assignment of tests to nodes with their predicted durations.
+// Durations are in (fractional) seconds.
+type Plan struct {
+	Created time.Time `json:"created"`
+	Nodes   []Node    `json:"nodes"`
+	// parameters of the cost model of the split
+	MaxFunctions int `json:"max_functions,omitempty"`
+	TestParallel int `json:"test_parallel,omitempty"`
+}
+
+// Node is the assignment of a node
+type Node struct {
+	Index     int     `json:"index"`
+	Predicted float64 `json:"predicted_seconds"` // including the package overheads
+	Tests     []Test  `json:"tests"`
+}
+
+// Test is a test (or a part of a test split by subtests) assigned to a node
+type Test struct {
+	Package   string  `json:"package"`
+	Function  string  `json:"function"`
+	Part      int     `json:"part,omitempty"`
+	Predicted float64 `json:"predicted_seconds"`
+	// how the cost model of the split runs the test
+	Parallel   bool `json:"parallel,omitempty"`   // calls t.Parallel()
+	Standalone bool `json:"standalone,omitempty"` // runs in its own invocation
+}
+
+// Save writes the plan as JSON
+func Save(path string, p *Plan) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode plan: %w", err)
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// Load reads a plan written by Save
+func Load(path string) (*Plan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var p Plan
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("failed to decode plan %s: %w", path, err)
+	}
+	return &p, nil
+}
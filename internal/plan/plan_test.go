@@ -0,0 +1,91 @@
+package plan
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/takuo/go-testsplitter/internal/history"
+)
+
+func TestSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	want := &Plan{
+		Created: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		Nodes: []Node{{Index: 0, Predicted: 1.5, Tests: []Test{
+			{Package: "pkg", Function: "TestA", Predicted: 0.5},
+			{Package: "pkg", Function: "TestB", Part: 1, Predicted: 1},
+		}}},
+	}
+	require.NoError(t, Save(path, want))
+	got, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+}
+
+func TestCompare(t *testing.T) {
+	created := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	p := &Plan{
+		Created:      created,
+		TestParallel: 2,
+		Nodes: []Node{
+			{Index: 0, Predicted: 7, Tests: []Test{
+				{Package: "pkg", Function: "TestA", Predicted: 2},
+				{Package: "pkg", Function: "TestLong", Part: 0, Predicted: 1, Standalone: true},
+				{Package: "pkg", Function: "TestLong", Part: 1, Predicted: 2, Standalone: true},
+			}},
+			{Index: 1, Predicted: 4, Tests: []Test{
+				{Package: "pkg", Function: "TestB", Predicted: 3},
+				{Package: "pkg", Function: "TestMissing", Predicted: 1},
+			}},
+			{Index: 2, Predicted: 4, Tests: []Test{
+				{Package: "pkg", Function: "TestP1", Predicted: 2, Parallel: true},
+				{Package: "pkg", Function: "TestP2", Predicted: 2, Parallel: true},
+			}},
+		},
+	}
+	at := func(sec int) time.Time { return created.Add(time.Duration(sec) * time.Second) }
+	results := map[int]history.History{
+		0: {
+			"pkg:TestA":        {{Duration: 2 * time.Second, Time: at(3)}},
+			"pkg:TestLong":     {{Duration: 4 * time.Second, Time: at(8)}, {Duration: 5 * time.Second, Time: at(9)}},
+			"pkg:TestLong/sub": {{Duration: 4 * time.Second, Time: at(8)}},
+			"pkg:":             {{Duration: time.Second, Time: at(10)}},
+		},
+		1: {
+			"pkg:TestB": {
+				{Duration: 3 * time.Second, Time: at(4)},
+				{Duration: 7 * time.Second, Time: created.Add(-time.Hour)}, // previous run
+			},
+		},
+		2: {
+			"pkg:TestP1": {{Duration: 5 * time.Second, Time: at(5), Parallel: true}},
+			"pkg:TestP2": {{Duration: 5 * time.Second, Time: at(5), Parallel: true}},
+		},
+	}
+
+	acc := Compare(p, results)
+	assert.Equal(t, []NodeAccuracy{
+		// TestA and the parts of TestLong each pay the 1s overhead
+		{Index: 0, Predicted: 7 * time.Second, Actual: 14 * time.Second, Sum: 12 * time.Second, Wall: 8 * time.Second, Tests: 2},
+		{Index: 1, Predicted: 4 * time.Second, Actual: 3 * time.Second, Sum: 3 * time.Second, Wall: 3 * time.Second, Tests: 2, Missing: 1},
+		// the parallel tests overlap
+		{Index: 2, Predicted: 4 * time.Second, Actual: 5 * time.Second, Sum: 10 * time.Second, Wall: 5 * time.Second, Tests: 2},
+	}, acc.Nodes)
+	assert.InDelta(t, 1.0, acc.Nodes[0].RelError(), 1e-9)
+	assert.InDelta(t, 0.25, acc.Nodes[2].RelError(), 1e-9)
+
+	require.Len(t, acc.Tests, 5)
+	assert.Equal(t, TestAccuracy{Node: 0, Package: "pkg", Function: "TestLong", Predicted: 3 * time.Second, Actual: 9 * time.Second}, acc.Tests[0],
+		"parts are added up and the biggest misprediction comes first")
+	assert.InDelta(t, 2.0, acc.Tests[0].RelError(), 1e-9)
+}
+
+func TestImbalance(t *testing.T) {
+	assert.InDelta(t, 1.0, Imbalance([]time.Duration{time.Second, time.Second}), 1e-9)
+	assert.InDelta(t, 1.5, Imbalance([]time.Duration{time.Second, 3 * time.Second, 2 * time.Second}), 1e-9)
+	assert.Zero(t, Imbalance(nil))
+}
@@ -0,0 +1,155 @@
+package scanner
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/build"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// cacheVersion is the version of the scan cache format. It must be increased
+// whenever the analysis of a file changes, so stale results are not reused.
+const cacheVersion = 1
+
+// cacheFile is the on-disk representation of a Cache, stored as gzip-compressed JSON
+type cacheFile struct {
+	Version int                   `json:"version"`
+	Files   map[string]*fileTests `json:"files"`
+}
+
+// Cache holds the scan results of test files keyed by the hash of their
+// content and the build context, so unchanged files are not parsed again.
+// A nil *Cache caches nothing. It is safe for concurrent use.
+type Cache struct {
+	mu      sync.Mutex
+	entries map[string]*fileTests
+	used    map[string]bool
+	hits    int
+	misses  int
+}
+
+// NewCache returns an empty cache
+func NewCache() *Cache {
+	return &Cache{entries: make(map[string]*fileTests), used: make(map[string]bool)}
+}
+
+// LoadCache reads a cache written by Save. A missing file, or one written by
+// another version, results in an empty cache.
+func LoadCache(path string) (*Cache, error) {
+	c := NewCache()
+	fp, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer fp.Close()
+
+	zr, err := gzip.NewReader(fp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scan cache %s: %w", path, err)
+	}
+	defer zr.Close()
+
+	var data cacheFile
+	if err := json.NewDecoder(zr).Decode(&data); err != nil {
+		return nil, fmt.Errorf("failed to decode scan cache %s: %w", path, err)
+	}
+	if data.Version == cacheVersion && data.Files != nil {
+		c.entries = data.Files
+	}
+	return c, nil
+}
+
+// Save writes the entries used since the cache was loaded to path atomically,
+// dropping the ones of files that were changed or removed
+func (c *Cache) Save(path string) error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	data := cacheFile{Version: cacheVersion, Files: make(map[string]*fileTests, len(c.used))}
+	for key := range c.used {
+		data.Files[key] = c.entries[key]
+	}
+	c.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create directory for scan cache: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to create scan cache: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	zw := gzip.NewWriter(tmp)
+	if err := json.NewEncoder(zw).Encode(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to encode scan cache: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write scan cache: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write scan cache: %w", err)
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Stats returns the number of files found in the cache and parsed
+func (c *Cache) Stats() (hits, misses int) {
+	if c == nil {
+		return 0, 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hits, c.misses
+}
+
+func (c *Cache) get(key string) (*fileTests, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ft, ok := c.entries[key]
+	if ok {
+		c.hits++
+		c.used[key] = true
+	} else {
+		c.misses++
+	}
+	return ft, ok
+}
+
+func (c *Cache) put(key string, ft *fileTests) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = ft
+	c.used[key] = true
+}
+
+// cacheKey identifies the scan result of a file by its content and the parts
+// of the build context the result depends on
+func cacheKey(ctx *build.Context, src []byte) string {
+	tags := slices.Sorted(slices.Values(ctx.BuildTags))
+	h := sha256.New()
+	fmt.Fprintf(h, "%d\x00%s\x00%s\x00%s\x00", cacheVersion, ctx.GOOS, ctx.GOARCH, strings.Join(tags, ","))
+	h.Write(src)
+	return hex.EncodeToString(h.Sum(nil))
+}
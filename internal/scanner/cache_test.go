@@ -0,0 +1,97 @@
+package scanner
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanTests_Cache(t *testing.T) {
+	base := t.TempDir()
+	pkg1 := filepath.Join(base, "pkg1")
+	pkg2 := filepath.Join(base, "pkg2")
+	for path, src := range map[string]string{
+		filepath.Join(pkg1, "user_test.go"): `package example
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type UserSuite struct{ suite.Suite }
+
+func TestUserSuite(t *testing.T) {
+	suite.Run(t, new(UserSuite))
+}
+`,
+		filepath.Join(pkg1, "methods_test.go"): "package example\n\nfunc (s *UserSuite) TestCreate() {}\n",
+		filepath.Join(pkg2, "a_test.go"):       "package example\n\nimport \"testing\"\n\n//testsplitter:weight=1m\nfunc TestA(t *testing.T) {\n\tt.Parallel()\n}\n",
+		// the same content in another package is cached once and keeps its path
+		filepath.Join(pkg2, "b_test.go"): "package example\n\nfunc (s *UserSuite) TestCreate() {}\n",
+	} {
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(src), 0o644))
+	}
+	packages := []string{pkg1, pkg2}
+	want, err := ScanTests(nil, packages)
+	require.NoError(t, err)
+
+	cachePath := filepath.Join(t.TempDir(), "scan.json.gz")
+	cache, err := LoadCache(cachePath)
+	require.NoError(t, err)
+	got, err := ScanTests(nil, packages, WithCache(cache), WithConcurrency(2))
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+	hits, misses := cache.Stats()
+	assert.Equal(t, 1, hits)
+	assert.Equal(t, 3, misses)
+	require.NoError(t, cache.Save(cachePath))
+
+	// unchanged files are not parsed again
+	cache, err = LoadCache(cachePath)
+	require.NoError(t, err)
+	got, err = ScanTests(nil, packages, WithCache(cache))
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+	hits, misses = cache.Stats()
+	assert.Equal(t, 4, hits)
+	assert.Equal(t, 0, misses)
+	assert.Equal(t, []string{"TestCreate"}, got[pkg1][0].SuiteMethods)
+
+	// a changed file is parsed again
+	require.NoError(t, os.WriteFile(filepath.Join(pkg1, "methods_test.go"), []byte("package example\n\nfunc (s *UserSuite) TestDelete() {}\n"), 0o644))
+	cache, err = LoadCache(cachePath)
+	require.NoError(t, err)
+	got, err = ScanTests(nil, packages, WithCache(cache))
+	require.NoError(t, err)
+	hits, misses = cache.Stats()
+	assert.Equal(t, 3, hits)
+	assert.Equal(t, 1, misses)
+	assert.Equal(t, []string{"TestDelete"}, got[pkg1][0].SuiteMethods)
+	assert.Equal(t, want[pkg2], got[pkg2])
+}
+
+func TestLoadCache_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scan.json.gz")
+	require.NoError(t, os.WriteFile(path, []byte("not gzip"), 0o644))
+	_, err := LoadCache(path)
+	assert.Error(t, err)
+
+	// a cache of another version is discarded
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	require.NoError(t, json.NewEncoder(zw).Encode(cacheFile{Version: cacheVersion + 1, Files: map[string]*fileTests{"key": {Package: "example"}}}))
+	require.NoError(t, zw.Close())
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o644))
+	cache, err := LoadCache(path)
+	require.NoError(t, err)
+	_, ok := cache.get("key")
+	assert.False(t, ok)
+}
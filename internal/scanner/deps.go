@@ -0,0 +1,69 @@
+package scanner
+
+import (
+	"fmt"
+	"os"
+	"slices"
+)
+
+// depFields are the fields of listedPackage requested to build the import graph
+const depFields = "-json=ImportPath,Dir,Module,Error,ForTest,Imports," +
+	"GoFiles,CgoFiles,CFiles,CXXFiles,HFiles,SFiles,SysoFiles,IgnoredGoFiles," +
+	"TestGoFiles,XTestGoFiles,EmbedFiles,TestEmbedFiles,XTestEmbedFiles"
+
+// depInfo is the part of the output of go list describing the imports and files of a package
+type depInfo struct {
+	ForTest string
+	Imports []string
+
+	GoFiles, CgoFiles, CFiles, CXXFiles, HFiles, SFiles, SysoFiles, IgnoredGoFiles []string
+	TestGoFiles, XTestGoFiles                                                      []string
+	EmbedFiles, TestEmbedFiles, XTestEmbedFiles                                    []string
+}
+
+// Dependency is a package in the import graph of the tests, as listed by
+// go list -deps -test. The packages compiled for a test binary have an import
+// path like "example.com/foo [example.com/bar.test]".
+type Dependency struct {
+	ImportPath string
+	Dir        string // absolute, empty for missing packages
+	ForTest    string // the package whose test binary the package is compiled for
+	ModuleDir  string // empty outside of modules
+	Imports    []string
+	// Files are the names of the source, test and embedded files relative to Dir
+	Files []string
+}
+
+// ListDependencies lists the packages and every package they import,
+// including the imports of their tests, in all of their modules.
+// The flags are passed to go list, e.g. -tags, and the environment variables
+// are added to the current ones, e.g. GOOS and GOARCH.
+func ListDependencies(importPaths, env []string, flags ...string) ([]Dependency, error) {
+	if len(importPaths) == 0 {
+		return nil, nil
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("%w: could not get current directory", err)
+	}
+	listed, err := goListModules(cwd, env, importPaths, slices.Concat([]string{depFields, "-deps", "-test"}, flags)...)
+	if err != nil {
+		return nil, err
+	}
+	deps := make([]Dependency, 0, len(listed))
+	for _, pkg := range listed {
+		dep := Dependency{
+			ImportPath: pkg.ImportPath,
+			Dir:        pkg.Dir,
+			ForTest:    pkg.ForTest,
+			Imports:    pkg.Imports,
+			Files: slices.Concat(pkg.GoFiles, pkg.CgoFiles, pkg.CFiles, pkg.CXXFiles, pkg.HFiles, pkg.SFiles, pkg.SysoFiles,
+				pkg.IgnoredGoFiles, pkg.TestGoFiles, pkg.XTestGoFiles, pkg.EmbedFiles, pkg.TestEmbedFiles, pkg.XTestEmbedFiles),
+		}
+		if pkg.Module != nil {
+			dep.ModuleDir = pkg.Module.Dir
+		}
+		deps = append(deps, dep)
+	}
+	return deps, nil
+}
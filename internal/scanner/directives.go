@@ -0,0 +1,134 @@
+package scanner
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// directivePrefix starts the comment directives giving scheduling hints
+const directivePrefix = "//testsplitter:"
+
+// Directives are the scheduling hints given by //testsplitter: comments,
+// either in the doc comment of a test function or before the package clause
+// for every test in the file.
+//
+//	//testsplitter:weight=90s  use this duration instead of the history
+//	//testsplitter:isolate     run alone, not concurrently with other tests
+//	//testsplitter:node=0      run on the given node
+//	//testsplitter:group=db    run on the same node as the other tests of the group
+//	//testsplitter:label=slow  add labels for --select and --skip, separated by commas
+type Directives struct {
+	Weight  time.Duration // 0 if not set
+	Isolate bool
+	Node    int // valid if Pinned
+	Pinned  bool
+	Group   string
+	Labels  []string
+}
+
+// merge returns the directives overridden by the ones set in other
+func (d Directives) merge(other Directives) Directives {
+	if other.Weight > 0 {
+		d.Weight = other.Weight
+	}
+	if other.Isolate {
+		d.Isolate = true
+	}
+	if other.Pinned {
+		d.Node, d.Pinned = other.Node, true
+	}
+	if other.Group != "" {
+		d.Group = other.Group
+	}
+	if len(other.Labels) > 0 {
+		// d may share the labels with the directives of the file
+		d.Labels = slices.Clone(d.Labels)
+		d.addLabels(other.Labels)
+	}
+	return d
+}
+
+// addLabels adds the labels not added yet
+func (d *Directives) addLabels(labels []string) {
+	for _, label := range labels {
+		if !slices.Contains(d.Labels, label) {
+			d.Labels = append(d.Labels, label)
+		}
+	}
+}
+
+// fileDirectives returns the directives in the comments before the package clause
+func fileDirectives(fset *token.FileSet, file *ast.File) Directives {
+	var d Directives
+	for _, cg := range file.Comments {
+		if cg.Pos() > file.Package {
+			break
+		}
+		d = d.merge(parseDirectives(fset, cg))
+	}
+	return d
+}
+
+// parseDirectives parses the directives in a comment group.
+// Invalid directives are logged and ignored.
+func parseDirectives(fset *token.FileSet, cg *ast.CommentGroup) Directives {
+	var d Directives
+	if cg == nil {
+		return d
+	}
+	for _, c := range cg.List {
+		text, ok := strings.CutPrefix(c.Text, directivePrefix)
+		if !ok {
+			continue
+		}
+		if err := d.set(strings.TrimSpace(text)); err != nil {
+			log.Printf("Ignoring directive at %s: %v", fset.Position(c.Pos()), err)
+		}
+	}
+	return d
+}
+
+// set applies a single directive, e.g. "weight=90s"
+func (d *Directives) set(directive string) (err error) {
+	name, value, hasValue := strings.Cut(directive, "=")
+	switch name {
+	case "weight":
+		if d.Weight, err = time.ParseDuration(value); err != nil || d.Weight <= 0 {
+			d.Weight = 0
+			return fmt.Errorf("invalid weight %q", value)
+		}
+	case "isolate":
+		if hasValue {
+			return fmt.Errorf("isolate takes no value")
+		}
+		d.Isolate = true
+	case "node":
+		if d.Node, err = strconv.Atoi(value); err != nil || d.Node < 0 {
+			d.Node = 0
+			return fmt.Errorf("invalid node %q", value)
+		}
+		d.Pinned = true
+	case "group":
+		if value == "" {
+			return fmt.Errorf("empty group")
+		}
+		d.Group = value
+	case "label":
+		labels := strings.Split(value, ",")
+		for i, label := range labels {
+			if labels[i] = strings.TrimSpace(label); labels[i] == "" {
+				return fmt.Errorf("invalid labels %q", value)
+			}
+		}
+		d.addLabels(labels)
+	default:
+		return fmt.Errorf("unknown directive %q", name)
+	}
+	return nil
+}
@@ -2,59 +2,412 @@
 package scanner
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"go/ast"
+	"go/build"
+	"go/build/constraint"
+	"go/doc"
 	"go/parser"
+	"go/printer"
 	"go/token"
-	"io/fs"
 	"log"
 	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
 	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/sourcegraph/conc/pool"
 )
 
+// TestFunc describes a test function found in a package
+type TestFunc struct {
+	Name     string
+	File     string // path of the file declaring the function
+	Stmts    int    // number of statements in the function body, including nested ones
+	Parallel bool   // the function calls t.Parallel() itself
+	// Fingerprint identifies the function body regardless of the function name,
+	// comments and line breaks, so a renamed test can be matched to its history
+	Fingerprint string
+	// SuiteMethods are the Test* methods of the testify suites run by the
+	// function with suite.Run, which run as its subtests
+	SuiteMethods []string
+	Ginkgo       bool // the function bootstraps a Ginkgo v2 suite with RunSpecs
+	Directives   Directives
+	// Tags are the build tags of the context required by the //go:build line
+	// of the file, e.g. "integration" for a file built only with -tags integration
+	Tags []string
+}
+
 // ScanTestFunctions scans the specified Go packages for test functions.
 func ScanTestFunctions(packages []string) (funcs map[string][]string, err error) {
-	funcs = make(map[string][]string)
-	fset := token.NewFileSet()
+	tests, err := ScanTests(nil, packages)
+	if err != nil {
+		return nil, err
+	}
+	funcs = make(map[string][]string, len(tests))
+	for pkg, fns := range tests {
+		for _, fn := range fns {
+			funcs[pkg] = append(funcs[pkg], fn.Name)
+		}
+	}
+	return funcs, nil
+}
+
+// Option configures ScanTests
+type Option func(*options)
+
+type options struct {
+	concurrency int
+	cache       *Cache
+}
+
+// WithConcurrency sets the number of packages scanned at once (default: GOMAXPROCS)
+func WithConcurrency(n int) Option {
+	return func(o *options) {
+		if n > 0 {
+			o.concurrency = n
+		}
+	}
+}
 
+// WithCache reuses the results of unchanged test files from the cache and
+// records the results of the others in it
+func WithCache(cache *Cache) Option {
+	return func(o *options) {
+		o.cache = cache
+	}
+}
+
+// ScanTests scans the specified Go packages for test functions, fuzz targets
+// and examples with an output comment, all of which are selected by -test.run.
+// Files excluded by build constraints (//go:build lines and _GOOS_GOARCH
+// suffixes) in the build context are skipped; a nil ctx means build.Default.
+// Packages are scanned concurrently.
+func ScanTests(ctx *build.Context, packages []string, opts ...Option) (tests map[string][]TestFunc, err error) {
+	if ctx == nil {
+		ctx = &build.Default
+	}
+	o := options{concurrency: runtime.GOMAXPROCS(0)}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	type result struct {
+		pkg       string
+		functions []TestFunc
+	}
+	// token.FileSet is safe for concurrent use
+	fset := token.NewFileSet()
+	p := pool.NewWithResults[result]().WithMaxGoroutines(o.concurrency)
 	for _, pkg := range packages {
-		log.Printf("Parsing package: %s", pkg)
+		p.Go(func() result {
+			return result{pkg: pkg, functions: scanPackage(ctx, fset, o.cache, pkg)}
+		})
+	}
 
-		// Check if directory exists
-		if _, err := os.Stat(pkg); os.IsNotExist(err) {
-			log.Printf("Package directory %s does not exist, skipping", pkg)
-			continue
+	tests = make(map[string][]TestFunc)
+	for _, r := range p.Wait() {
+		if len(r.functions) > 0 {
+			tests[r.pkg] = r.functions
 		}
+	}
+	log.Printf("Found test functions in %d packages", len(tests))
+	return tests, nil
+}
+
+// scanPackage scans the test files of a package directory
+func scanPackage(ctx *build.Context, fset *token.FileSet, cache *Cache, pkg string) []TestFunc {
+	log.Printf("Parsing package: %s", pkg)
 
-		// Parse Go files in the package directory
-		pkgs, err := parser.ParseDir(fset, pkg, func(info fs.FileInfo) bool {
-			return strings.HasSuffix(info.Name(), "_test.go")
-		}, parser.ParseComments)
+	entries, err := os.ReadDir(pkg)
+	if os.IsNotExist(err) {
+		log.Printf("Package directory %s does not exist, skipping", pkg)
+		return nil
+	}
+	if err != nil {
+		log.Printf("Failed to read package %s: %v, skipping", pkg, err)
+		return nil
+	}
+
+	var files []*fileTests
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), "_test.go") {
+			continue
+		}
+		match, err := ctx.MatchFile(pkg, entry.Name())
+		if err != nil {
+			log.Printf("Failed to evaluate build constraints of %s: %v", filepath.Join(pkg, entry.Name()), err)
+		}
+		if !match {
+			continue
+		}
+		ft, err := scanFile(ctx, fset, cache, filepath.Join(pkg, entry.Name()))
 		if err != nil {
 			log.Printf("Failed to parse package %s: %v, skipping", pkg, err)
+			return nil
+		}
+		files = append(files, ft)
+	}
+
+	// testify suites may declare their methods in other files of the package
+	methods := make(map[string]map[string][]string) // package name -> type -> methods
+	for _, ft := range files {
+		if methods[ft.Package] == nil {
+			methods[ft.Package] = make(map[string][]string)
+		}
+		for typ, names := range ft.Methods {
+			methods[ft.Package][typ] = append(methods[ft.Package][typ], names...)
+		}
+	}
+	var functions []TestFunc
+	for _, ft := range files {
+		for _, f := range ft.Functions {
+			tf := f.TestFunc
+			if len(f.SuiteTypes) > 0 {
+				for _, typ := range f.SuiteTypes {
+					tf.SuiteMethods = append(tf.SuiteMethods, methods[ft.Package][typ]...)
+				}
+				slices.Sort(tf.SuiteMethods)
+				tf.SuiteMethods = slices.Compact(tf.SuiteMethods)
+			}
+			functions = append(functions, tf)
+		}
+	}
+
+	if len(functions) > 0 {
+		log.Printf("Found %d test functions in package %s", len(functions), pkg)
+	} else {
+		log.Printf("No test functions found in package %s", pkg)
+	}
+	return functions
+}
+
+// fileTests is the result of scanning a single test file, which depends only
+// on its content and the build tags, so it can be cached
+type fileTests struct {
+	Package   string              `json:"package"` // name in the package clause
+	Functions []fileFunc          `json:"functions,omitempty"`
+	Methods   map[string][]string `json:"methods,omitempty"` // Test* methods by receiver type
+}
+
+// fileFunc is a test function of a file with the suite types it runs,
+// whose methods may be declared in other files
+type fileFunc struct {
+	TestFunc
+	SuiteTypes []string `json:"suiteTypes,omitempty"`
+}
+
+// scanFile scans a test file, or takes its result from the cache if unchanged
+func scanFile(ctx *build.Context, fset *token.FileSet, cache *Cache, path string) (*fileTests, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key := cacheKey(ctx, src)
+	ft, ok := cache.get(key)
+	if !ok {
+		file, err := parser.ParseFile(fset, path, src, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		ft = analyzeFile(ctx, fset, file)
+		cache.put(key, ft)
+	}
+	// the same content may be cached for another path
+	result := *ft
+	result.Functions = slices.Clone(ft.Functions)
+	for i := range result.Functions {
+		result.Functions[i].File = path
+	}
+	return &result, nil
+}
+
+// analyzeFile finds the test functions, fuzz targets and examples with output of a file
+func analyzeFile(ctx *build.Context, fset *token.FileSet, file *ast.File) *fileTests {
+	ft := &fileTests{Package: file.Name.Name, Methods: testMethods([]*ast.File{file})}
+	testing := testingName(file)
+	suite := suiteName(file)
+	ginkgo := ginkgoName(file)
+	directives := fileDirectives(fset, file)
+	tags := buildTags(ctx, file)
+	examples := make(map[string]TestFunc)
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Recv != nil {
+			continue
+		}
+		name := fn.Name.Name
+		tf := TestFunc{
+			Name:        name,
+			Stmts:       countStmts(fn.Body),
+			Parallel:    callsParallel(fn),
+			Fingerprint: fingerprint(fn),
+			Directives:  directives.merge(parseDirectives(fset, fn.Doc)),
+			Tags:        tags,
+		}
+		switch {
+		case isTest(name, "Test") && hasSignature(fn, testing, "T"):
+			tf.Ginkgo = callsRunSpecs(fn, ginkgo)
+			ft.Functions = append(ft.Functions, fileFunc{TestFunc: tf, SuiteTypes: suiteTypes(fn, suite)})
+		case isTest(name, "Fuzz") && hasSignature(fn, testing, "F"):
+			ft.Functions = append(ft.Functions, fileFunc{TestFunc: tf})
+		case strings.HasPrefix(name, "Example"):
+			examples[name] = tf
+		}
+	}
+	// go test runs only the examples with an output comment
+	for _, ex := range doc.Examples(file) {
+		if tf, ok := examples["Example"+ex.Name]; ok && (ex.Output != "" || ex.EmptyOutput) {
+			ft.Functions = append(ft.Functions, fileFunc{TestFunc: tf})
+		}
+	}
+	return ft
+}
+
+// isTest reports whether name looks like a test (or fuzz target) name with
+// the prefix, using the rule of go test: the prefix must not be followed by a
+// lower-case letter, so TestFoo, Test_foo and Test are tests but Testing is not.
+func isTest(name, prefix string) bool {
+	rest, ok := strings.CutPrefix(name, prefix)
+	if !ok {
+		return false
+	}
+	if rest == "" {
+		return true
+	}
+	r, _ := utf8.DecodeRuneInString(rest)
+	return !unicode.IsLower(r)
+}
+
+// testingName returns the name the file imports the testing package as,
+// "." for a dot import, or "" if the file does not import it
+func testingName(file *ast.File) string {
+	for _, imp := range file.Imports {
+		if path, err := strconv.Unquote(imp.Path.Value); err != nil || path != "testing" {
 			continue
 		}
+		if imp.Name == nil {
+			return "testing"
+		}
+		if imp.Name.Name != "_" {
+			return imp.Name.Name
+		}
+	}
+	return ""
+}
 
-		var functions []string
-		for _, astPkg := range pkgs {
-			for _, file := range astPkg.Files {
-				ast.Inspect(file, func(n ast.Node) bool {
-					if fn, ok := n.(*ast.FuncDecl); ok {
-						if fn.Name.IsExported() && strings.HasPrefix(fn.Name.Name, "Test") && fn.Name.Name != "Test" && fn.Name.Name != "TestMain" {
-							functions = append(functions, fn.Name.Name)
-						}
-					}
-					return true
-				})
+// hasSignature reports whether fn is a non-generic func(*testing.<typ>) without results
+func hasSignature(fn *ast.FuncDecl, testing, typ string) bool {
+	ft := fn.Type
+	if testing == "" || ft.TypeParams != nil || ft.Results != nil || len(ft.Params.List) != 1 || len(ft.Params.List[0].Names) > 1 {
+		return false
+	}
+	star, ok := ft.Params.List[0].Type.(*ast.StarExpr)
+	if !ok {
+		return false
+	}
+	if testing == "." {
+		id, ok := star.X.(*ast.Ident)
+		return ok && id.Name == typ
+	}
+	sel, ok := star.X.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != typ {
+		return false
+	}
+	pkg, ok := sel.X.(*ast.Ident)
+	return ok && pkg.Name == testing
+}
+
+// countStmts counts the statements in a function body
+func countStmts(body *ast.BlockStmt) (n int) {
+	if body == nil {
+		return 0
+	}
+	ast.Inspect(body, func(node ast.Node) bool {
+		switch node.(type) {
+		case *ast.BlockStmt:
+		case ast.Stmt:
+			n++
+		}
+		return true
+	})
+	return n
+}
+
+// fingerprint returns a short hash of the parameters and body of a function
+func fingerprint(fn *ast.FuncDecl) string {
+	h := sha256.New()
+	// printing a node other than a file omits the comments, and printing it
+	// without positions ignores the original line breaks
+	fset := token.NewFileSet()
+	if err := printer.Fprint(h, fset, fn.Type); err != nil {
+		return ""
+	}
+	if fn.Body != nil {
+		if err := printer.Fprint(h, fset, fn.Body); err != nil {
+			return ""
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// callsParallel reports whether the test function calls Parallel() on its
+// *testing.T parameter. Calls inside closures (e.g. subtests) are ignored.
+func callsParallel(fn *ast.FuncDecl) (found bool) {
+	params := fn.Type.Params.List
+	if fn.Body == nil || len(params) != 1 || len(params[0].Names) != 1 {
+		return false
+	}
+	t := params[0].Names[0].Name
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.CallExpr:
+			sel, ok := n.Fun.(*ast.SelectorExpr)
+			if !ok || sel.Sel.Name != "Parallel" || len(n.Args) != 0 {
+				break
+			}
+			if x, ok := sel.X.(*ast.Ident); ok && x.Name == t {
+				found = true
 			}
 		}
+		return !found
+	})
+	return found
+}
 
-		if len(functions) > 0 {
-			funcs[pkg] = functions
-			log.Printf("Found %d test functions in package %s", len(functions), pkg)
-		} else {
-			log.Printf("No test functions found in package %s", pkg)
+// buildTags returns the tags of the context required by the //go:build line of the file
+func buildTags(ctx *build.Context, file *ast.File) []string {
+	var tags []string
+	var required func(constraint.Expr)
+	required = func(x constraint.Expr) {
+		switch x := x.(type) {
+		case *constraint.TagExpr:
+			if slices.Contains(ctx.BuildTags, x.Tag) && !slices.Contains(tags, x.Tag) {
+				tags = append(tags, x.Tag)
+			}
+		case *constraint.AndExpr:
+			required(x.X)
+			required(x.Y)
+		case *constraint.OrExpr:
+			required(x.X)
+			required(x.Y)
 		}
 	}
-	log.Printf("Found test functions in %d packages", len(funcs))
-	return funcs, nil
+	for _, cg := range file.Comments {
+		if cg.Pos() > file.Package {
+			break
+		}
+		for _, c := range cg.List {
+			if expr, err := constraint.Parse(c.Text); err == nil && constraint.IsGoBuild(c.Text) {
+				required(expr)
+			}
+		}
+	}
+	return tags
 }
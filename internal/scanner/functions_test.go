@@ -0,0 +1,342 @@
+package scanner
+
+import (
+	"go/build"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanTests_Parallel(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "example_test.go"), []byte(`package example
+
+import "testing"
+
+func TestParallel(tt *testing.T) {
+	tt.Parallel()
+}
+
+func TestSubtestsParallel(t *testing.T) {
+	t.Run("sub", func(t *testing.T) {
+		t.Parallel()
+	})
+}
+
+func TestSerial(t *testing.T) {
+	var x struct{ Parallel func() }
+	x.Parallel = func() {}
+	x.Parallel()
+}
+`), 0o644))
+
+	tests, err := ScanTests(nil, []string{dir})
+	require.NoError(t, err)
+
+	parallel := make(map[string]bool)
+	for _, tf := range tests[dir] {
+		parallel[tf.Name] = tf.Parallel
+		assert.Equal(t, filepath.Join(dir, "example_test.go"), tf.File)
+	}
+	assert.Equal(t, map[string]bool{
+		"TestParallel":         true,
+		"TestSubtestsParallel": false,
+		"TestSerial":           false,
+	}, parallel)
+}
+
+func TestScanTests_Fingerprint(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a_test.go"), []byte(`package example
+
+import "testing"
+
+func TestOld(t *testing.T) {
+	if 1+1 != 2 {
+		t.Fatal("math")
+	}
+}
+
+func TestOther(t *testing.T) {
+	if 1+1 != 3 {
+		t.Fatal("math")
+	}
+}
+`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b_test.go"), []byte(`package example
+
+import "testing"
+
+// TestRenamed is TestOld with another name, comments and line breaks
+func TestRenamed(t *testing.T) {
+	// check
+	if 1+1 != 2 { t.Fatal("math") }
+}
+`), 0o644))
+
+	tests, err := ScanTests(nil, []string{dir})
+	require.NoError(t, err)
+
+	fingerprints := make(map[string]string)
+	for _, tf := range tests[dir] {
+		assert.Len(t, tf.Fingerprint, 16)
+		fingerprints[tf.Name] = tf.Fingerprint
+	}
+	assert.Equal(t, fingerprints["TestOld"], fingerprints["TestRenamed"])
+	assert.NotEqual(t, fingerprints["TestOld"], fingerprints["TestOther"])
+}
+
+func TestScanTests_BuildConstraints(t *testing.T) {
+	dir := t.TempDir()
+	for name, src := range map[string]string{
+		"common_test.go":      "package example\n\nimport \"testing\"\n\nfunc TestCommon(t *testing.T) {}\n",
+		"integration_test.go": "//go:build integration\n\npackage example\n\nimport \"testing\"\n\nfunc TestIntegration(t *testing.T) {}\n",
+		"os_windows_test.go":  "package example\n\nimport \"testing\"\n\nfunc TestWindows(t *testing.T) {}\n",
+		"os_linux_test.go":    "package example\n\nimport \"testing\"\n\nfunc TestLinux(t *testing.T) {}\n",
+	} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(src), 0o644))
+	}
+
+	scan := func(ctx *build.Context) []string {
+		tests, err := ScanTests(ctx, []string{dir})
+		require.NoError(t, err)
+		var names []string
+		for _, tf := range tests[dir] {
+			names = append(names, tf.Name)
+		}
+		return names
+	}
+
+	linux := build.Default
+	linux.GOOS = "linux"
+	assert.ElementsMatch(t, []string{"TestCommon", "TestLinux"}, scan(&linux))
+
+	windows := build.Default
+	windows.GOOS = "windows"
+	windows.BuildTags = []string{"integration"}
+	assert.ElementsMatch(t, []string{"TestCommon", "TestWindows", "TestIntegration"}, scan(&windows))
+
+	tests, err := ScanTests(&windows, []string{dir})
+	require.NoError(t, err)
+	tags := make(map[string][]string)
+	for _, tf := range tests[dir] {
+		tags[tf.Name] = tf.Tags
+	}
+	assert.Equal(t, []string{"integration"}, tags["TestIntegration"], "the file requires the tag")
+	assert.Empty(t, tags["TestCommon"])
+}
+
+func TestScanTests_ExamplesAndFuzz(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "example_test.go"), []byte(`package example_test
+
+import (
+	"fmt"
+	"testing"
+)
+
+func ExampleHello() {
+	fmt.Println("hello")
+	// Output: hello
+}
+
+func ExampleHello_empty() {
+	// Output:
+}
+
+func ExampleNoOutput() {
+	fmt.Println("not run by go test")
+}
+
+func FuzzParse(f *testing.F) {
+	f.Add("seed")
+	f.Fuzz(func(t *testing.T, s string) {})
+}
+
+func TestHello(t *testing.T) {}
+`), 0o644))
+
+	tests, err := ScanTests(nil, []string{dir})
+	require.NoError(t, err)
+
+	var names []string
+	for _, tf := range tests[dir] {
+		names = append(names, tf.Name)
+	}
+	assert.ElementsMatch(t, []string{"ExampleHello", "ExampleHello_empty", "FuzzParse", "TestHello"}, names)
+}
+
+func TestScanTests_Signatures(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a_test.go"), []byte(`package example
+
+import (
+	"os"
+	tt "testing"
+)
+
+type Suite struct{}
+
+func (s *Suite) TestMethod(t *tt.T) {}
+
+func Test(t *tt.T)        {}
+func Test_underscore(t *tt.T) {}
+func TestAlias(t *tt.T)   {}
+func TestWrongParam(s string) {}
+func TestWithResult(t *tt.T) error { return nil }
+func TestGeneric[T any](t *tt.T) {}
+func Testing(t *tt.T)     {}
+func TestMain(m *tt.M)    { os.Exit(m.Run()) }
+func TestFuzzType(f *tt.F) {}
+func FuzzAlias(f *tt.F)   {}
+`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b_test.go"), []byte(`package example
+
+import . "testing"
+
+func TestDot(t *T) {}
+func TestNotTesting(t *testingT) {}
+
+type testingT struct{}
+`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "c_test.go"), []byte(`package example
+
+type testing struct{ T int }
+
+func TestNoImport(t *testing.T) {}
+`), 0o644))
+
+	tests, err := ScanTests(nil, []string{dir})
+	require.NoError(t, err)
+
+	var names []string
+	for _, tf := range tests[dir] {
+		names = append(names, tf.Name)
+	}
+	assert.ElementsMatch(t, []string{"Test", "Test_underscore", "TestAlias", "FuzzAlias", "TestDot"}, names)
+}
+
+func TestScanTests_Suites(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "suite_test.go"), []byte(`package example
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type UserSuite struct{ suite.Suite }
+
+func (s *UserSuite) SetupTest()    {}
+func (s *UserSuite) TestCreate()   {}
+func (s *UserSuite) TestDelete()   {}
+
+func TestUserSuite(t *testing.T) {
+	suite.Run(t, new(UserSuite))
+}
+
+func TestVarSuite(t *testing.T) {
+	s := &OrderSuite{}
+	suite.Run(t, s)
+}
+
+func TestPlain(t *testing.T) {}
+`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "order_test.go"), []byte(`package example
+
+import ts "github.com/stretchr/testify/suite"
+
+type OrderSuite struct{ ts.Suite }
+
+func (s OrderSuite) TestPlace() {}
+`), 0o644))
+
+	tests, err := ScanTests(nil, []string{dir})
+	require.NoError(t, err)
+
+	methods := make(map[string][]string)
+	for _, tf := range tests[dir] {
+		methods[tf.Name] = tf.SuiteMethods
+	}
+	assert.Equal(t, map[string][]string{
+		"TestUserSuite": {"TestCreate", "TestDelete"},
+		"TestVarSuite":  {"TestPlace"},
+		"TestPlain":     nil,
+	}, methods)
+}
+
+func TestScanTests_Ginkgo(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "books_suite_test.go"), []byte(`package books_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestBooks(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Books Suite")
+}
+
+func TestPlain(t *testing.T) {}
+`), 0o644))
+
+	tests, err := ScanTests(nil, []string{dir})
+	require.NoError(t, err)
+
+	ginkgo := make(map[string]bool)
+	for _, tf := range tests[dir] {
+		ginkgo[tf.Name] = tf.Ginkgo
+	}
+	assert.Equal(t, map[string]bool{"TestBooks": true, "TestPlain": false}, ginkgo)
+}
+
+func TestScanTests_Directives(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "db_test.go"), []byte(`//testsplitter:group=db
+//testsplitter:node=1
+
+package example
+
+import "testing"
+
+//testsplitter:weight=90s
+//testsplitter:isolate
+//testsplitter:label=slow, migration
+func TestMigrate(t *testing.T) {}
+
+// TestQuery has a doc comment.
+//
+//testsplitter:node=0
+//testsplitter:weight=abc
+//testsplitter:unknown
+func TestQuery(t *testing.T) {}
+`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "plain_test.go"), []byte(`package example
+
+import "testing"
+
+func TestPlain(t *testing.T) {}
+`), 0o644))
+
+	tests, err := ScanTests(nil, []string{dir})
+	require.NoError(t, err)
+
+	directives := make(map[string]Directives)
+	for _, tf := range tests[dir] {
+		directives[tf.Name] = tf.Directives
+	}
+	assert.Equal(t, map[string]Directives{
+		"TestMigrate": {Weight: 90 * time.Second, Isolate: true, Node: 1, Pinned: true, Group: "db", Labels: []string{"slow", "migration"}},
+		"TestQuery":   {Node: 0, Pinned: true, Group: "db"},
+		"TestPlain":   {},
+	}, directives)
+}
@@ -0,0 +1,48 @@
+package scanner
+
+import (
+	"go/ast"
+	"strconv"
+)
+
+// ginkgoImportPath is the import path of Ginkgo v2
+const ginkgoImportPath = "github.com/onsi/ginkgo/v2"
+
+// ginkgoName returns the name the file imports Ginkgo v2 as, "." for a dot
+// import, or "" if the file does not import it
+func ginkgoName(file *ast.File) string {
+	for _, imp := range file.Imports {
+		if path, err := strconv.Unquote(imp.Path.Value); err != nil || path != ginkgoImportPath {
+			continue
+		}
+		if imp.Name == nil {
+			return "ginkgo"
+		}
+		if imp.Name.Name != "_" {
+			return imp.Name.Name
+		}
+	}
+	return ""
+}
+
+// callsRunSpecs reports whether the function bootstraps a Ginkgo suite with RunSpecs
+func callsRunSpecs(fn *ast.FuncDecl, ginkgo string) (found bool) {
+	if ginkgo == "" || fn.Body == nil {
+		return false
+	}
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if found || !ok {
+			return !found
+		}
+		switch f := call.Fun.(type) {
+		case *ast.Ident:
+			found = ginkgo == "." && f.Name == "RunSpecs"
+		case *ast.SelectorExpr:
+			pkg, ok := f.X.(*ast.Ident)
+			found = ok && pkg.Name == ginkgo && f.Sel.Name == "RunSpecs"
+		}
+		return !found
+	})
+	return found
+}
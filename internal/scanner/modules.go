@@ -0,0 +1,209 @@
+package scanner
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/build"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Module is a Go module whose packages are tested
+type Module struct {
+	Path string
+	Dir  string // absolute
+}
+
+// FindModules returns the modules to test from dir: the modules of the go.work
+// workspace if dir is in one, otherwise the module containing dir and every
+// module nested under it.
+func FindModules(dir string) ([]Module, error) {
+	gowork, err := goEnv(dir, "GOWORK")
+	if err != nil {
+		return nil, err
+	}
+	if gowork != "" && gowork != "off" {
+		return workspaceModules(dir)
+	}
+
+	var modules []Module
+	if gomod, err := goEnv(dir, "GOMOD"); err == nil && gomod != "" && gomod != os.DevNull {
+		if m, err := readModule(filepath.Dir(gomod)); err == nil {
+			modules = append(modules, m)
+		}
+	}
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			// the directories ignored by the go command
+			name := d.Name()
+			if path != dir && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "testdata" || name == "vendor") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Name() != "go.mod" || slices.ContainsFunc(modules, func(m Module) bool { return m.Dir == filepath.Dir(path) }) {
+			return nil
+		}
+		m, err := readModule(filepath.Dir(path))
+		if err != nil {
+			return err
+		}
+		modules = append(modules, m)
+		return nil
+	})
+	return modules, err
+}
+
+// goEnv returns the value of a go env variable in dir
+func goEnv(dir, name string) (string, error) {
+	cmd := exec.Command("go", "env", name)
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("%w: failed to run go env %s", err, name)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// workspaceModules lists the modules of the go.work workspace of dir
+func workspaceModules(dir string) ([]Module, error) {
+	cmd := exec.Command("go", "list", "-m", "-json")
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to list workspace modules: %v", err, stderr.String())
+	}
+	var modules []Module
+	dec := json.NewDecoder(bytes.NewReader(output))
+	for {
+		var m Module
+		if err := dec.Decode(&m); err == io.EOF {
+			return modules, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal module info: %w", err)
+		}
+		modules = append(modules, m)
+	}
+}
+
+// readModule reads the module path from the go.mod file in dir
+func readModule(dir string) (Module, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return Module{}, err
+	}
+	for line := range strings.Lines(string(data)) {
+		line, _, _ = strings.Cut(line, "//")
+		path, ok := strings.CutPrefix(strings.TrimSpace(line), "module")
+		if !ok || path == "" || (path[0] != ' ' && path[0] != '\t' && path[0] != '"') {
+			continue
+		}
+		path = strings.TrimSpace(path)
+		if unquoted, err := strconv.Unquote(path); err == nil {
+			path = unquoted
+		}
+		return Module{Path: path, Dir: dir}, nil
+	}
+	return Module{}, fmt.Errorf("no module path in %s", filepath.Join(dir, "go.mod"))
+}
+
+// splitPatterns assigns package patterns relative to cwd, or import path
+// patterns, to the modules to run go list in, keyed by the module directory.
+// A directory belongs to the innermost module containing it; a recursive
+// pattern also covers the modules nested under its directory.
+// Patterns that belong to no module are kept in cwd.
+func splitPatterns(cwd string, modules []Module, patterns []string) map[string][]string {
+	byDir := make(map[string][]string)
+	add := func(dir, pattern string) {
+		if !slices.Contains(byDir[dir], pattern) {
+			byDir[dir] = append(byDir[dir], pattern)
+		}
+	}
+	for _, pattern := range patterns {
+		if !build.IsLocalImport(pattern) && !filepath.IsAbs(pattern) {
+			// an import path pattern belongs to the modules it may match
+			literal, _, wildcard := strings.Cut(pattern, "...")
+			var matched bool
+			var owner *Module
+			for i, m := range modules {
+				switch {
+				case wildcard && strings.HasPrefix(m.Path, literal):
+					add(m.Dir, pattern)
+					matched = true
+				case pattern == m.Path || strings.HasPrefix(literal, m.Path+"/"):
+					if owner == nil || len(m.Path) > len(owner.Path) {
+						owner = &modules[i]
+					}
+				}
+			}
+			if owner != nil {
+				add(owner.Dir, pattern)
+			} else if !matched {
+				add(cwd, pattern)
+			}
+			continue
+		}
+
+		base, recursive := pattern, false
+		if rest, ok := strings.CutSuffix(pattern, "..."); ok {
+			base, recursive = strings.TrimSuffix(rest, "/"), true
+		}
+		if !filepath.IsAbs(base) {
+			base = filepath.Join(cwd, base)
+		}
+		var owner *Module
+		for i, m := range modules {
+			if within(m.Dir, base) && (owner == nil || len(m.Dir) > len(owner.Dir)) {
+				owner = &modules[i]
+			}
+		}
+		if owner != nil {
+			rel, _ := filepath.Rel(owner.Dir, base)
+			p := "./" + filepath.ToSlash(rel)
+			switch {
+			case rel == "." && recursive:
+				p = "./..."
+			case rel == ".":
+				p = "."
+			case recursive:
+				p += "/..."
+			}
+			add(owner.Dir, p)
+		}
+		if !recursive {
+			if owner == nil {
+				add(cwd, pattern)
+			}
+			continue
+		}
+		nested := false
+		for _, m := range modules {
+			if (owner == nil || m.Dir != owner.Dir) && within(base, m.Dir) {
+				add(m.Dir, "./...")
+				nested = true
+			}
+		}
+		if owner == nil && !nested {
+			add(cwd, pattern)
+		}
+	}
+	return byDir
+}
+
+// within reports whether path is dir or below it
+func within(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && filepath.IsLocal(rel)
+}
@@ -1,63 +1,176 @@
 package scanner
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
+	"log"
+	"maps"
 	"os"
 	"os/exec"
 	"path/filepath"
-	"regexp"
+	"slices"
 	"strings"
 )
 
-// ScanPackages scans the specified directory for Go packages, excluding any that match the given pattern.
-func ScanPackages(excludePattern string) ([]string, error) {
-	var excludeRegex *regexp.Regexp
-	var err error
+// Package is a Go package to test. Its import path is the identity of the
+// package everywhere, as go test -json records it; the directory is where the
+// test binary runs and where the test files are scanned.
+type Package struct {
+	ImportPath string
+	Dir        string // relative to the working directory if below it
+	Module     string // path of the module, empty outside of modules
+}
+
+// Aliases returns the other names the results of the package may be recorded
+// with: its directory and its import path relative to the module
+func (p Package) Aliases() []string {
+	aliases := []string{filepath.ToSlash(p.Dir)}
+	if rel, ok := strings.CutPrefix(p.ImportPath, p.Module+"/"); ok && p.Module != "" && rel != aliases[0] {
+		aliases = append(aliases, rel)
+	}
+	return aliases
+}
+
+// listedPackage is the output of go list -json
+type listedPackage struct {
+	ImportPath string
+	Dir        string
+	Module     *struct{ Path, Dir string }
+	Error      *struct{ Err string }
+	depInfo    // listed with depFields only
+}
+
+// listFields are the fields of listedPackage requested from go list
+const listFields = "-json=ImportPath,Dir,Module,Error"
+
+// goList runs go list -e in dir with the environment variables added to the
+// current ones. The arguments include the -json flag.
+func goList(dir string, env []string, args ...string) ([]listedPackage, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("go", append([]string{"list", "-e"}, args...)...)
+	cmd.Dir = dir
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to run go list: %v", err, stderr.String())
+	}
+	var packages []listedPackage
+	dec := json.NewDecoder(bytes.NewReader(output))
+	for {
+		var pkg listedPackage
+		if err := dec.Decode(&pkg); err == io.EOF {
+			return packages, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal package info: %w", err)
+		}
+		packages = append(packages, pkg)
+	}
+}
 
-	if excludePattern != "" {
-		excludeRegex, err = regexp.Compile(excludePattern)
+// newPackage converts the output of go list, making the directory relative to cwd
+func newPackage(cwd string, listed listedPackage) Package {
+	pkg := Package{ImportPath: strings.TrimSuffix(listed.ImportPath, ".test"), Dir: listed.Dir}
+	if rel, err := filepath.Rel(cwd, listed.Dir); err == nil && filepath.IsLocal(rel) {
+		pkg.Dir = rel
+	}
+	if listed.Module != nil {
+		pkg.Module = listed.Module.Path
+	}
+	return pkg
+}
+
+// goListModules runs go list in every module the patterns belong to, so
+// nested modules and the modules of a go.work workspace are listed in their
+// own context. Without modules, go list runs once in cwd.
+func goListModules(cwd string, env, patterns []string, flags ...string) ([]listedPackage, error) {
+	modules, err := FindModules(cwd)
+	if err != nil {
+		log.Printf("Failed to find modules, listing packages in %s only: %v", cwd, err)
+	}
+	byDir := splitPatterns(cwd, modules, patterns)
+	var listed []listedPackage
+	seen := make(map[string]bool)
+	for _, dir := range slices.Sorted(maps.Keys(byDir)) {
+		pkgs, err := goList(dir, env, slices.Concat(flags, byDir[dir])...)
 		if err != nil {
-			return nil, fmt.Errorf("invalid exclude pattern: %v", err)
+			return nil, err
+		}
+		for _, pkg := range pkgs {
+			if !seen[pkg.ImportPath] {
+				seen[pkg.ImportPath] = true
+				listed = append(listed, pkg)
+			}
 		}
 	}
+	return listed, nil
+}
+
+// ScanPackages scans the packages matching the patterns (./... if none) for Go
+// packages with tests. Every module in a go.work workspace or nested under the
+// current directory is scanned.
+func ScanPackages(patterns ...string) ([]Package, error) {
 	cwd, err := os.Getwd()
 	if err != nil {
 		return nil, fmt.Errorf("%w: could not get current directory", err)
 	}
-	cmd := exec.Command("go", "list", "-test", "-f", `{"Name":"{{ .Name }}","Dir":"{{.Dir}}","Root":"{{.Root}}","ImportPath":"{{.ImportPath}}"}`, "./...")
-	cmd.Dir = cwd
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return nil, fmt.Errorf("%w: failed to run go list: %v", err, string(output))
+	if len(patterns) == 0 {
+		patterns = []string{"./..."}
 	}
-	type Package struct {
-		Dir        string
-		ImportPath string
-		Root       string
+	listed, err := goListModules(cwd, nil, patterns, listFields, "-test")
+	if err != nil {
+		return nil, err
 	}
 
-	lines := strings.Split(string(output), "\n")
-	packages := make([]string, 0, len(lines)/2)
-	for _, line := range lines {
-		if line == "" {
-			continue
-		}
-		var pkg Package
-		if err := json.Unmarshal([]byte(line), &pkg); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal package info: %w", err)
-		}
+	packages := make([]Package, 0, len(listed)/2)
+	for _, pkg := range listed {
+		// only packages with tests have a test main package
 		if !strings.HasSuffix(pkg.ImportPath, ".test") {
 			continue
 		}
-		if excludeRegex != nil && excludeRegex.MatchString(pkg.ImportPath) {
-			continue
+		packages = append(packages, newPackage(cwd, pkg))
+	}
+	return packages, nil
+}
+
+// ResolvePackages resolves the packages given either as directories or as
+// import paths (like the output of go list) to their import paths and directories.
+// Packages that cannot be found are logged and skipped.
+func ResolvePackages(names []string) ([]Package, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("%w: could not get current directory", err)
+	}
+	args := make([]string, len(names))
+	for i, name := range names {
+		args[i] = name
+		// go list takes only paths starting with . or / as directories
+		if info, err := os.Stat(name); err == nil && info.IsDir() && !filepath.IsAbs(name) {
+			args[i] = "./" + filepath.ToSlash(filepath.Clean(name))
 		}
-		relPath, err := filepath.Rel(pkg.Root, pkg.Dir)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get relative path for %s: %w", pkg.Dir, err)
+	}
+	listed, err := goListModules(cwd, nil, args, listFields)
+	if err != nil {
+		return nil, err
+	}
+	packages := make([]Package, 0, len(listed))
+	for _, pkg := range listed {
+		if pkg.Dir == "" {
+			if pkg.Error != nil {
+				log.Printf("Package %s not found, skipping: %s", pkg.ImportPath, pkg.Error.Err)
+			} else {
+				log.Printf("Package %s not found, skipping", pkg.ImportPath)
+			}
+			continue
 		}
-		packages = append(packages, relPath)
+		packages = append(packages, newPackage(cwd, pkg))
 	}
-	return packages, err
+	return packages, nil
 }
@@ -4,7 +4,6 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
-	"slices"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -45,27 +44,133 @@ func main() {}
 	cmd = exec.Command("go", "mod", "tidy")
 	output, err = cmd.CombinedOutput()
 	require.NoError(t, err, "Failed to tidy go module: %s", output)
+	t.Setenv("GOPROXY", "off")
 
-	// 除外パターンなし
-	got, err := ScanPackages("")
+	got, err := ScanPackages()
 	require.NoError(t, err)
-	want := []string{
-		filepath.Join("pkg1"),
-		filepath.Join("pkg2"),
-		filepath.Join("pkg2", "subpkg"),
+	want := []Package{
+		{ImportPath: "example.com/testpkg/pkg1", Dir: "pkg1", Module: "example.com/testpkg"},
+		{ImportPath: "example.com/testpkg/pkg2", Dir: "pkg2", Module: "example.com/testpkg"},
+		{ImportPath: "example.com/testpkg/pkg2/subpkg", Dir: filepath.Join("pkg2", "subpkg"), Module: "example.com/testpkg"},
 	}
-	slices.Sort(got)
-	slices.Sort(want)
-	assert.Equal(t, want, got)
+	assert.ElementsMatch(t, want, got)
 
-	// 除外パターンあり
-	got, err = ScanPackages("subpkg")
+	// ディレクトリとインポートパスの両方を解決
+	got, err = ResolvePackages([]string{"pkg1", "example.com/testpkg/pkg2/subpkg", "example.com/testpkg/missing"})
 	require.NoError(t, err)
-	want = []string{
-		filepath.Join("pkg1"),
-		filepath.Join("pkg2"),
+	assert.Equal(t, []Package{want[0], want[2]}, got)
+	assert.Equal(t, []string{"pkg2/subpkg"}, got[1].Aliases())
+}
+
+// writeModule creates a module with a package with tests in each of the dirs
+func writeModule(t *testing.T, dir, path string, pkgs ...string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(dir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module "+path+"\n\ngo 1.24\n"), 0o644))
+	for _, pkg := range pkgs {
+		pkgDir := filepath.Join(dir, pkg)
+		name := filepath.Base(pkgDir)
+		require.NoError(t, os.MkdirAll(pkgDir, 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(pkgDir, name+"_test.go"), []byte("package "+name+"\n"), 0o644))
+	}
+}
+
+func TestScanPackages_NestedModules(t *testing.T) {
+	baseDir := t.TempDir()
+	writeModule(t, baseDir, "example.com/root", "api")
+	writeModule(t, filepath.Join(baseDir, "services", "billing"), "example.com/billing", "invoice", "tax")
+	writeModule(t, filepath.Join(baseDir, "tools"), "example.com/tools", "lint")
+	t.Chdir(baseDir)
+	t.Setenv("GOWORK", "")
+	t.Setenv("GOFLAGS", "-mod=mod")
+
+	importPaths := func(pkgs []Package) []string {
+		var paths []string
+		for _, pkg := range pkgs {
+			paths = append(paths, pkg.ImportPath)
+		}
+		return paths
+	}
+
+	got, err := ScanPackages()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"example.com/root/api", "example.com/billing/invoice", "example.com/billing/tax", "example.com/tools/lint"}, importPaths(got))
+	for _, pkg := range got {
+		if pkg.ImportPath == "example.com/billing/tax" {
+			assert.Equal(t, Package{ImportPath: "example.com/billing/tax", Dir: filepath.Join("services", "billing", "tax"), Module: "example.com/billing"}, pkg)
+		}
+	}
+
+	got, err = ScanPackages("./services/...", "./tools/lint")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"example.com/billing/invoice", "example.com/billing/tax", "example.com/tools/lint"}, importPaths(got))
+
+	got, err = ResolvePackages([]string{"services/billing/tax", "example.com/tools/lint", "api"})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"example.com/billing/tax", "example.com/tools/lint", "example.com/root/api"}, importPaths(got))
+}
+
+func TestScanPackages_Workspace(t *testing.T) {
+	baseDir := t.TempDir()
+	writeModule(t, filepath.Join(baseDir, "a"), "example.com/a", "foo")
+	writeModule(t, filepath.Join(baseDir, "b"), "example.com/b", "bar")
+	writeModule(t, filepath.Join(baseDir, "unused"), "example.com/unused", "baz")
+	require.NoError(t, os.WriteFile(filepath.Join(baseDir, "go.work"), []byte("go 1.24\n\nuse (\n\t./a\n\t./b\n)\n"), 0o644))
+	t.Chdir(baseDir)
+	t.Setenv("GOWORK", "")
+	t.Setenv("GOFLAGS", "")
+
+	modules, err := FindModules(baseDir)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []Module{
+		{Path: "example.com/a", Dir: filepath.Join(baseDir, "a")},
+		{Path: "example.com/b", Dir: filepath.Join(baseDir, "b")},
+	}, modules)
+
+	got, err := ScanPackages()
+	require.NoError(t, err)
+	var paths []string
+	for _, pkg := range got {
+		paths = append(paths, pkg.ImportPath)
+	}
+	assert.ElementsMatch(t, []string{"example.com/a/foo", "example.com/b/bar"}, paths)
+}
+
+func TestSplitPatterns(t *testing.T) {
+	modules := []Module{
+		{Path: "example.com/root", Dir: "/repo"},
+		{Path: "example.com/billing", Dir: "/repo/services/billing"},
+	}
+	assert.Equal(t, map[string][]string{
+		"/repo":                  {"./...", "./services/api", "example.com/root/api"},
+		"/repo/services/billing": {"./...", "./tax/...", "example.com/billing/..."},
+	}, splitPatterns("/repo", modules, []string{"./...", "./services/api", "./services/billing/tax/...", "example.com/root/api", "example.com/billing/..."}))
+	assert.Equal(t, map[string][]string{
+		"/repo/services/billing": {"./..."},
+	}, splitPatterns("/repo/services", modules, []string{"./billing/..."}))
+	assert.Equal(t, map[string][]string{
+		"/elsewhere": {"./...", "github.com/other/pkg"},
+	}, splitPatterns("/elsewhere", modules, []string{"./...", "github.com/other/pkg"}))
+}
+
+func TestListDependencies_Env(t *testing.T) {
+	baseDir := t.TempDir()
+	writeModule(t, baseDir, "example.com/root", "app", "winapi")
+	require.NoError(t, os.WriteFile(filepath.Join(baseDir, "app", "app_windows.go"),
+		[]byte("package app\n\nimport _ \"example.com/root/winapi\"\n"), 0o644))
+	t.Chdir(baseDir)
+	t.Setenv("GOWORK", "")
+
+	imports := func(env []string) []string {
+		deps, err := ListDependencies([]string{"example.com/root/app"}, env)
+		require.NoError(t, err)
+		for _, dep := range deps {
+			if dep.ImportPath == "example.com/root/app" {
+				return dep.Imports
+			}
+		}
+		return nil
 	}
-	slices.Sort(got)
-	slices.Sort(want)
-	assert.Equal(t, want, got)
+	assert.Empty(t, imports([]string{"GOOS=linux"}))
+	assert.Equal(t, []string{"example.com/root/winapi"}, imports([]string{"GOOS=windows"}), "files of the target platform should be listed")
 }
@@ -0,0 +1,120 @@
+package scanner
+
+import (
+	"go/ast"
+	"strconv"
+	"strings"
+)
+
+// suiteImportPath is the import path of testify's suite package
+const suiteImportPath = "github.com/stretchr/testify/suite"
+
+// suiteName returns the name the file imports testify's suite package as,
+// or "" if the file does not import it
+func suiteName(file *ast.File) string {
+	for _, imp := range file.Imports {
+		if path, err := strconv.Unquote(imp.Path.Value); err != nil || path != suiteImportPath {
+			continue
+		}
+		if imp.Name == nil {
+			return "suite"
+		}
+		if imp.Name.Name != "_" && imp.Name.Name != "." {
+			return imp.Name.Name
+		}
+	}
+	return ""
+}
+
+// suiteTypes returns the names of the suite types run by suite.Run calls in
+// the function, e.g. MySuite for suite.Run(t, new(MySuite)), suite.Run(t, &MySuite{})
+// or s := &MySuite{}; suite.Run(t, s).
+func suiteTypes(fn *ast.FuncDecl, suite string) (types []string) {
+	if suite == "" || fn.Body == nil {
+		return nil
+	}
+	// types of the variables assigned in the function
+	vars := make(map[string]string)
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.AssignStmt:
+			for i, lhs := range n.Lhs {
+				if id, ok := lhs.(*ast.Ident); ok && len(n.Rhs) == len(n.Lhs) {
+					if typ := typeName(n.Rhs[i], nil); typ != "" {
+						vars[id.Name] = typ
+					}
+				}
+			}
+		case *ast.ValueSpec:
+			for i, id := range n.Names {
+				if typ := typeName(n.Type, nil); typ != "" {
+					vars[id.Name] = typ
+				} else if i < len(n.Values) {
+					if typ := typeName(n.Values[i], nil); typ != "" {
+						vars[id.Name] = typ
+					}
+				}
+			}
+		}
+		return true
+	})
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || len(call.Args) != 2 {
+			return true
+		}
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok || sel.Sel.Name != "Run" {
+			return true
+		}
+		if pkg, ok := sel.X.(*ast.Ident); !ok || pkg.Name != suite {
+			return true
+		}
+		if typ := typeName(call.Args[1], vars); typ != "" {
+			types = append(types, typ)
+		}
+		return true
+	})
+	return types
+}
+
+// typeName returns the name of the local type of a suite expression:
+// new(T), &T{...}, T{...}, *T, T or a variable of such a type
+func typeName(expr ast.Expr, vars map[string]string) string {
+	switch e := expr.(type) {
+	case *ast.CallExpr:
+		if id, ok := e.Fun.(*ast.Ident); ok && id.Name == "new" && len(e.Args) == 1 {
+			return typeName(e.Args[0], nil)
+		}
+	case *ast.UnaryExpr:
+		return typeName(e.X, nil)
+	case *ast.StarExpr:
+		return typeName(e.X, nil)
+	case *ast.CompositeLit:
+		return typeName(e.Type, nil)
+	case *ast.Ident:
+		if vars != nil {
+			return vars[e.Name]
+		}
+		return e.Name
+	}
+	return ""
+}
+
+// testMethods returns the Test* methods of every type declared in the files,
+// which testify runs as subtests of the function calling suite.Run
+func testMethods(files []*ast.File) map[string][]string {
+	methods := make(map[string][]string)
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv == nil || len(fn.Recv.List) != 1 || !strings.HasPrefix(fn.Name.Name, "Test") {
+				continue
+			}
+			if typ := typeName(fn.Recv.List[0].Type, nil); typ != "" {
+				methods[typ] = append(methods[typ], fn.Name.Name)
+			}
+		}
+	}
+	return methods
+}
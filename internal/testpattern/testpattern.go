@@ -0,0 +1,78 @@
+// Package testpattern builds -test.run and -test.skip patterns.
+package testpattern
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Run returns a pattern matching exactly the given top-level tests
+func Run(names []string) string {
+	return "^(" + strings.Join(names, "|") + ")$"
+}
+
+// Subtests returns a pattern matching exactly the given first-level subtests of parent
+func Subtests(parent string, subtests []string) string {
+	quoted := make([]string, len(subtests))
+	for i, name := range subtests {
+		quoted[i] = Quote(Rewrite(name))
+	}
+	return "^" + parent + "$/^(" + strings.Join(quoted, "|") + ")$"
+}
+
+// Exact returns a pattern matching exactly the given texts, e.g. Ginkgo spec texts
+func Exact(texts []string) string {
+	quoted := make([]string, len(texts))
+	for i, text := range texts {
+		quoted[i] = Quote(text)
+	}
+	return "^(" + strings.Join(quoted, "|") + ")$"
+}
+
+// Quote escapes a test name for use in a pattern. Besides regexp
+// metacharacters, quotes and slashes are escaped so the pattern can be
+// single-quoted in a shell script and is not split into levels by go test.
+func Quote(name string) string {
+	return quoteReplacer.Replace(regexp.QuoteMeta(name))
+}
+
+var quoteReplacer = strings.NewReplacer(`'`, `\x27`, `"`, `\x22`, `/`, `\x2f`)
+
+// Rewrite rewrites a subtest name the same way the testing package does:
+// spaces become underscores and non-printable characters are escaped.
+func Rewrite(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		switch {
+		case isSpace(r):
+			b.WriteByte('_')
+		case !strconv.IsPrint(r):
+			s := strconv.QuoteRune(r)
+			b.WriteString(s[1 : len(s)-1])
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// isSpace is the definition of white space used by the testing package
+func isSpace(r rune) bool {
+	if r < 0x2000 {
+		switch r {
+		// Note: not the same as Unicode Z class.
+		case '\t', '\n', '\v', '\f', '\r', ' ', 0x85, 0xA0, 0x1680:
+			return true
+		}
+	} else {
+		if r <= 0x200a {
+			return true
+		}
+		switch r {
+		case 0x2028, 0x2029, 0x202f, 0x205f, 0x3000:
+			return true
+		}
+	}
+	return false
+}
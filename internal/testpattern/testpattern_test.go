@@ -0,0 +1,43 @@
+package testpattern
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRewrite(t *testing.T) {
+	assert.Equal(t, "case_a", Rewrite("case a"))
+	assert.Equal(t, "tab_and_newline_", Rewrite("tab\tand newline\n"))
+	assert.Equal(t, `nul\x00`, Rewrite("nul\x00"))
+	assert.Equal(t, "already_rewritten#01", Rewrite("already_rewritten#01"))
+}
+
+func TestSubtests(t *testing.T) {
+	got := Subtests("TestX", []string{"case a", "1+1=2", "it's"})
+	assert.Equal(t, `^TestX$/^(case_a|1\+1=2|it\x27s)$`, got)
+	assert.NotContains(t, got, "'", "pattern should be safe to single-quote")
+
+	// every level must be a valid regexp matching the rewritten names
+	levels := strings.SplitN(got, "/", 2)
+	re := regexp.MustCompile(levels[1])
+	for _, name := range []string{"case_a", "1+1=2", "it's"} {
+		assert.True(t, re.MatchString(name), name)
+	}
+	assert.False(t, re.MatchString("case_b"))
+}
+
+func TestRun(t *testing.T) {
+	assert.Equal(t, "^(TestA|TestB)$", Run([]string{"TestA", "TestB"}))
+}
+
+func TestExact(t *testing.T) {
+	got := Exact([]string{"Books when it's long", "Books a/b (1)"})
+	assert.NotContains(t, got, "'", "pattern should be safe to single-quote")
+	re := regexp.MustCompile(got)
+	assert.True(t, re.MatchString("Books when it's long"))
+	assert.True(t, re.MatchString("Books a/b (1)"))
+	assert.False(t, re.MatchString("Books when it's long too"))
+}
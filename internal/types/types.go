@@ -3,9 +3,16 @@ package types
 import (
 	"encoding/xml"
 	"iter"
+	"strconv"
 	"time"
 )
 
+// TestSuites represents a JUnit XML document with multiple test suites
+type TestSuites struct {
+	XMLName xml.Name    `xml:"testsuites"`
+	Suites  []TestSuite `xml:"testsuite"`
+}
+
 // TestSuite represents a JUnit XML test suite
 type TestSuite struct {
 	XMLName   xml.Name   `xml:"testsuite"`
@@ -14,6 +21,7 @@ type TestSuite struct {
 	Failures  int        `xml:"failures,attr"`
 	Errors    int        `xml:"errors,attr"`
 	Time      float64    `xml:"time,attr"`
+	Timestamp string     `xml:"timestamp,attr"`
 	TestCases []TestCase `xml:"testcase"`
 }
 
@@ -25,6 +33,7 @@ type TestCase struct {
 	Time      float64  `xml:"time,attr"`
 	Failure   *Failure `xml:"failure,omitempty"`
 	Error     *Error   `xml:"error,omitempty"`
+	Skipped   *Skipped `xml:"skipped,omitempty"`
 }
 
 // Failure represents a JUnit XML test failure
@@ -33,6 +42,11 @@ type Failure struct {
 	Text    string `xml:",chardata"`
 }
 
+// Skipped represents a JUnit XML skipped test
+type Skipped struct {
+	Message string `xml:"message,attr"`
+}
+
 // Error represents a JUnit XML test error
 type Error struct {
 	Message string `xml:"message,attr"`
@@ -44,6 +58,34 @@ type TestInfo struct {
 	Package  string
 	Function string
 	Duration time.Duration
+	Parallel bool // the test calls t.Parallel()
+
+	// A test function split into parts by its first-level subtests runs
+	// only Subtests, or all subtests except SkipSubtests, in each part.
+	Part         int
+	Subtests     []string
+	SkipSubtests []string
+	Ginkgo       bool // Subtests and SkipSubtests are the texts of Ginkgo specs
+
+	// Scheduling hints given by //testsplitter: directives
+	Isolate bool // run alone, not concurrently with other tests
+	Node    int  // the node to run on, valid if Pinned
+	Pinned  bool
+	Group   string // tests of the same group run on the same node
+}
+
+// IsPartial reports whether the test runs only a part of its subtests
+func (t *TestInfo) IsPartial() bool {
+	return len(t.Subtests) > 0 || len(t.SkipSubtests) > 0
+}
+
+// Key returns the unique key of the test (or the part of it) for scheduling
+func (t *TestInfo) Key() string {
+	key := t.Package + ":" + t.Function
+	if t.IsPartial() {
+		key += "#" + strconv.Itoa(t.Part)
+	}
+	return key
 }
 
 // NodeTest represents a test assigned to a specific node
@@ -51,6 +93,8 @@ type NodeTest struct {
 	NodeIndex     int
 	TotalDuration time.Duration
 	Funcs         map[string][]string
+	Partials      []TestInfo // parts of tests split by subtests, each run in its own invocation
+	Isolated      []TestInfo // tests run one by one before the others
 	Flags         string
 }
 
@@ -66,7 +110,11 @@ type TemplateData struct {
 
 // TestLine represents a single line in the test script
 type TestLine struct {
-	Package     string
+	Package     string // directory of the package, as in the templates before import paths were used
+	ImportPath  string // import path of the package, identifying it in test2json and the history
 	TestPattern string
+	SkipPattern string // pattern for -test.skip, empty if nothing is skipped
+	Args        string // additional arguments for the test binary, e.g. Ginkgo filters
+	Isolate     bool   // run alone, not concurrently with other lines
 	Flags       string
 }
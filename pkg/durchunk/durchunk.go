@@ -2,9 +2,12 @@
 package durchunk
 
 import (
+	"encoding/json"
 	"iter"
 	"math"
 	"math/rand"
+	"slices"
+	"strings"
 	"time"
 )
 
@@ -14,46 +17,177 @@ type Chunk struct {
 	Total time.Duration `json:"total_seconds"`
 }
 
+// chunkJSON is the JSON representation of Chunk, with the total in seconds
+type chunkJSON struct {
+	Keys  []string `json:"keys"`
+	Total float64  `json:"total_seconds"`
+}
+
+// MarshalJSON encodes the total as (fractional) seconds
+func (c Chunk) MarshalJSON() ([]byte, error) {
+	return json.Marshal(chunkJSON{Keys: c.Keys, Total: c.Total.Seconds()})
+}
+
+// UnmarshalJSON decodes the total from (fractional) seconds
+func (c *Chunk) UnmarshalJSON(data []byte) error {
+	var v chunkJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	c.Keys = v.Keys
+	c.Total = time.Duration(v.Total * float64(time.Second))
+	return nil
+}
+
+// CostFunc returns the total duration of a chunk holding the given keys.
+// It allows costs that are not a plain sum of the key durations,
+// e.g. a fixed overhead per group of keys.
+type CostFunc func(keys []string) time.Duration
+
+// Option configures SplitBalanced
+type Option func(*options)
+
+// GroupCostFunc returns the duration of the keys of a chunk belonging to a group
+type GroupCostFunc func(group string, keys []string) time.Duration
+
+type options struct {
+	cost      CostFunc
+	groups    func(key string) []string
+	groupCost GroupCostFunc
+	pinned    map[string]int
+	rand      *rand.Rand
+}
+
+// WithCost sets the function computing the total duration of a chunk.
+// By default the total is the sum of the durations of its keys.
+func WithCost(cost CostFunc) Option {
+	return func(o *options) {
+		o.cost = cost
+	}
+}
+
+// WithGroupCost sets the total duration of a chunk to the sum of the costs of
+// the groups its keys belong to, e.g. the packages of tests sharing a start-up
+// overhead. A key may belong to several groups. Moving a key between chunks
+// recomputes only the costs of its groups, which is much cheaper than WithCost
+// for many keys.
+func WithGroupCost(groups func(key string) []string, cost GroupCostFunc) Option {
+	return func(o *options) {
+		o.groups, o.groupCost = groups, cost
+		o.cost = func(keys []string) time.Duration {
+			byGroup := make(map[string][]string)
+			for _, k := range keys {
+				for _, g := range groups(k) {
+					byGroup[g] = append(byGroup[g], k)
+				}
+			}
+			var total time.Duration
+			for g, keys := range byGroup {
+				total += cost(g, keys)
+			}
+			return total
+		}
+	}
+}
+
+// WithPinned pins keys to the chunk with the given index.
+// Pinned keys are never moved; indexes out of range are ignored.
+func WithPinned(pinned map[string]int) Option {
+	return func(o *options) {
+		o.pinned = pinned
+	}
+}
+
+// WithSeed seeds the randomized search, so the same data is always split the
+// same way. By default every call is seeded randomly.
+func WithSeed(seed int64) Option {
+	return func(o *options) {
+		o.rand = rand.New(rand.NewSource(seed))
+	}
+}
+
 type entry struct {
 	Key string
-	Dur int64
+	Dur int64 // nanoseconds
 }
 
 // SplitBalanced は map[string]time.Duration を指定したチャンク数に分割します。
 // - 合計時間を均等化
 // - 要素数に制約なし（最低1個以上）
-func SplitBalanced(data iter.Seq2[string, time.Duration], chunkCount int) []Chunk {
+// - WithCost でチャンクの合計時間の計算方法を変更可能
+// - WithGroupCost でグループごとの時間の合計をチャンクの合計時間とし、差分だけ再計算
+// - WithPinned でキーを特定のチャンクに固定可能
+// - WithSeed で同じデータを常に同じように分割可能
+// - chunkCount が1未満の場合は nil を返す
+func SplitBalanced(data iter.Seq2[string, time.Duration], chunkCount int, opts ...Option) []Chunk {
+	if chunkCount < 1 {
+		return nil
+	}
 	entries := []entry{}
 	globalDurMap := make(map[string]int64)
 	for k, v := range data {
-		sec := int64(v.Seconds())
-		globalDurMap[k] = sec
-		entries = append(entries, entry{Key: k, Dur: sec})
+		globalDurMap[k] = int64(v)
+		entries = append(entries, entry{Key: k, Dur: int64(v)})
 	}
 
-	chunks := greedyPartition(entries, chunkCount)
-	chunks = simulatedAnnealing(chunks, 50000, 1000.0, 0.01, globalDurMap)
+	// the order of data may be random, like a map
+	slices.SortFunc(entries, func(a, b entry) int { return strings.Compare(a.Key, b.Key) })
 
-	for i := range chunks {
-		totalSec := int64(0)
-		for _, k := range chunks[i].Keys {
-			totalSec += globalDurMap[k]
+	o := options{cost: sumCost(globalDurMap)}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.rand == nil {
+		o.rand = rand.New(rand.NewSource(rand.Int63()))
+	}
+
+	pinned := make(map[string]int, len(o.pinned))
+	for k, i := range o.pinned {
+		if _, ok := globalDurMap[k]; ok && i >= 0 && i < chunkCount {
+			pinned[k] = i
 		}
-		chunks[i].Total = time.Duration(totalSec) * time.Second
+	}
+
+	chunks := greedyPartition(o.rand, entries, chunkCount, pinned)
+	chunks = simulatedAnnealing(o.rand, chunks, 50000, 1000.0, 0.01, &o, pinned)
+
+	for i := range chunks {
+		chunks[i].Total = o.cost(chunks[i].Keys)
 	}
 
 	return chunks
 }
 
+// sumCost returns the default cost, the sum of the durations of the keys
+func sumCost(durMap map[string]int64) CostFunc {
+	return func(keys []string) time.Duration {
+		total := int64(0)
+		for _, k := range keys {
+			total += durMap[k]
+		}
+		return time.Duration(total)
+	}
+}
+
 // --------------------
 // 内部関数
 // --------------------
-func greedyPartition(entries []entry, m int) []Chunk {
-	rand.Shuffle(len(entries), func(i, j int) { entries[i], entries[j] = entries[j], entries[i] })
+func greedyPartition(r *rand.Rand, entries []entry, m int, pinned map[string]int) []Chunk {
+	r.Shuffle(len(entries), func(i, j int) { entries[i], entries[j] = entries[j], entries[i] })
 
 	chunks := make([]Chunk, m)
 	sums := make([]int64, m)
 	for _, e := range entries {
+		if i, ok := pinned[e.Key]; ok {
+			chunks[i].Keys = append(chunks[i].Keys, e.Key)
+			sums[i] += e.Dur
+			chunks[i].Total = time.Duration(sums[i])
+		}
+	}
+	for _, e := range entries {
+		if _, ok := pinned[e.Key]; ok {
+			continue
+		}
 		best := 0
 		for i := 1; i < m; i++ {
 			if sums[i] < sums[best] {
@@ -62,61 +196,63 @@ func greedyPartition(entries []entry, m int) []Chunk {
 		}
 		chunks[best].Keys = append(chunks[best].Keys, e.Key)
 		sums[best] += e.Dur
-		chunks[best].Total = time.Duration(sums[best]) * time.Second
+		chunks[best].Total = time.Duration(sums[best])
 	}
 	return chunks
 }
 
-func simulatedAnnealing(chunks []Chunk, iterations int, tempStart, tempEnd float64, durMap map[string]int64) []Chunk {
-	best := copyChunks(chunks)
-	bestScore := score(best)
-	current := copyChunks(chunks)
+func simulatedAnnealing(r *rand.Rand, chunks []Chunk, iterations int, tempStart, tempEnd float64, o *options, pinned map[string]int) []Chunk {
+	s := newState(chunks, o)
+	best := s.chunks()
+	bestScore := s.score()
 	currentScore := bestScore
 
 	for i := range iterations {
 		t := tempStart * math.Pow(tempEnd/tempStart, float64(i)/float64(iterations))
-		next := copyChunks(current)
 
-		if rand.Float64() < 0.5 {
-			from := rand.Intn(len(next))
-			if len(next[from].Keys) == 0 {
+		var undo func()
+		if r.Float64() < 0.5 {
+			from := r.Intn(len(s.keys))
+			if len(s.keys[from]) == 0 {
 				continue
 			}
-			to := rand.Intn(len(next))
+			to := r.Intn(len(s.keys))
 			if from == to {
 				continue
 			}
-			idx := rand.Intn(len(next[from].Keys))
-			val := next[from].Keys[idx]
-			next[from].Keys = append(next[from].Keys[:idx], next[from].Keys[idx+1:]...)
-			next[to].Keys = append(next[to].Keys, val)
+			idx := r.Intn(len(s.keys[from]))
+			if _, ok := pinned[s.keys[from][idx]]; ok {
+				continue
+			}
+			s.move(from, idx, to, len(s.keys[to]))
+			undo = func() { s.move(to, len(s.keys[to])-1, from, idx) }
 		} else {
-			a := rand.Intn(len(next))
-			b := rand.Intn(len(next))
-			if a == b || len(next[a].Keys) == 0 || len(next[b].Keys) == 0 {
+			a := r.Intn(len(s.keys))
+			b := r.Intn(len(s.keys))
+			if a == b || len(s.keys[a]) == 0 || len(s.keys[b]) == 0 {
 				continue
 			}
-			ia := rand.Intn(len(next[a].Keys))
-			ib := rand.Intn(len(next[b].Keys))
-			next[a].Keys[ia], next[b].Keys[ib] = next[b].Keys[ib], next[a].Keys[ia]
-		}
-
-		for i := range next {
-			sum := int64(0)
-			for _, k := range next[i].Keys {
-				sum += durMap[k]
+			ia := r.Intn(len(s.keys[a]))
+			ib := r.Intn(len(s.keys[b]))
+			_, pa := pinned[s.keys[a][ia]]
+			_, pb := pinned[s.keys[b][ib]]
+			if pa || pb {
+				continue
 			}
-			next[i].Total = time.Duration(sum) * time.Second
+			s.swap(a, ia, b, ib)
+			undo = func() { s.swap(a, ia, b, ib) }
 		}
 
-		nextScore := score(next)
-		delta := float64(nextScore - currentScore)
-		if delta < 0 || rand.Float64() < math.Exp(-delta/t) {
-			current = next
+		nextScore := s.score()
+		// the temperature is in seconds
+		delta := float64(nextScore-currentScore) / float64(time.Second)
+		if delta < 0 || r.Float64() < math.Exp(-delta/t) {
 			currentScore = nextScore
+		} else {
+			undo()
 		}
 		if currentScore < bestScore {
-			best = copyChunks(current)
+			best = s.chunks()
 			bestScore = currentScore
 		}
 	}
@@ -124,29 +260,118 @@ func simulatedAnnealing(chunks []Chunk, iterations int, tempStart, tempEnd float
 	return best
 }
 
-func score(chunks []Chunk) int64 {
-	min, max := chunks[0].Total.Seconds(), chunks[0].Total.Seconds()
-	for _, c := range chunks[1:] {
-		sec := c.Total.Seconds()
-		if sec < min {
-			min = sec
-		}
-		if sec > max {
-			max = sec
+// state is the assignment of the keys to the chunks explored by the annealing,
+// changed in place with the totals updated after every move
+type state struct {
+	keys   [][]string
+	totals []time.Duration
+	cost   CostFunc
+
+	// with WithGroupCost, only the costs of the groups of the moved keys are recomputed
+	groupCost GroupCostFunc
+	groups    map[string][]string        // key -> groups
+	members   []map[string][]string      // per chunk: group -> keys
+	costs     []map[string]time.Duration // per chunk: group -> cost
+}
+
+func newState(chunks []Chunk, o *options) *state {
+	s := &state{cost: o.cost, groupCost: o.groupCost}
+	for _, c := range chunks {
+		s.keys = append(s.keys, slices.Clone(c.Keys))
+		s.totals = append(s.totals, 0)
+	}
+	if o.groupCost != nil {
+		s.groups = make(map[string][]string)
+		for i, keys := range s.keys {
+			s.members = append(s.members, make(map[string][]string))
+			s.costs = append(s.costs, make(map[string]time.Duration))
+			for _, k := range keys {
+				s.groups[k] = o.groups(k)
+				for _, g := range s.groups[k] {
+					s.members[i][g] = append(s.members[i][g], k)
+				}
+			}
+			for g, keys := range s.members[i] {
+				s.costs[i][g] = s.groupCost(g, keys)
+				s.totals[i] += s.costs[i][g]
+			}
 		}
+		return s
+	}
+	for i, keys := range s.keys {
+		s.totals[i] = s.cost(keys)
 	}
-	return int64(max - min)
+	return s
 }
 
-func copyChunks(chunks []Chunk) []Chunk {
-	newChunks := make([]Chunk, len(chunks))
-	for i := range chunks {
-		keys := make([]string, len(chunks[i].Keys))
-		copy(keys, chunks[i].Keys)
-		newChunks[i] = Chunk{
-			Keys:  keys,
-			Total: chunks[i].Total,
-		}
+// move moves the key at index from of chunk a to index to of chunk b
+func (s *state) move(a, from, b, to int) {
+	key := s.keys[a][from]
+	s.keys[a] = slices.Delete(s.keys[a], from, from+1)
+	s.keys[b] = slices.Insert(s.keys[b], to, key)
+	if s.groupCost == nil {
+		s.totals[a], s.totals[b] = s.cost(s.keys[a]), s.cost(s.keys[b])
+		return
+	}
+	for _, g := range s.groups[key] {
+		i := slices.Index(s.members[a][g], key)
+		s.members[a][g] = slices.Delete(s.members[a][g], i, i+1)
+		s.members[b][g] = append(s.members[b][g], key)
+		s.update(a, g)
+		s.update(b, g)
+	}
+}
+
+// swap exchanges the key at index i of chunk a with the key at index j of chunk b
+func (s *state) swap(a, i, b, j int) {
+	ka, kb := s.keys[a][i], s.keys[b][j]
+	s.keys[a][i], s.keys[b][j] = kb, ka
+	if s.groupCost == nil {
+		s.totals[a], s.totals[b] = s.cost(s.keys[a]), s.cost(s.keys[b])
+		return
+	}
+	for _, g := range s.groups[ka] {
+		n := slices.Index(s.members[a][g], ka)
+		s.members[a][g] = slices.Delete(s.members[a][g], n, n+1)
+		s.members[b][g] = append(s.members[b][g], ka)
+	}
+	for _, g := range s.groups[kb] {
+		n := slices.Index(s.members[b][g], kb)
+		s.members[b][g] = slices.Delete(s.members[b][g], n, n+1)
+		s.members[a][g] = append(s.members[a][g], kb)
 	}
-	return newChunks
+	for _, g := range slices.Concat(s.groups[ka], s.groups[kb]) {
+		s.update(a, g)
+		s.update(b, g)
+	}
+}
+
+// update recomputes the cost of a group in a chunk and the total of the chunk
+func (s *state) update(c int, g string) {
+	var cost time.Duration
+	if len(s.members[c][g]) > 0 {
+		cost = s.groupCost(g, s.members[c][g])
+	} else {
+		delete(s.members[c], g)
+	}
+	s.totals[c] += cost - s.costs[c][g]
+	if cost == 0 {
+		delete(s.costs[c], g)
+	} else {
+		s.costs[c][g] = cost
+	}
+}
+
+// chunks returns a copy of the current assignment
+func (s *state) chunks() []Chunk {
+	chunks := make([]Chunk, len(s.keys))
+	for i, keys := range s.keys {
+		chunks[i] = Chunk{Keys: slices.Clone(keys), Total: s.totals[i]}
+	}
+	return chunks
+}
+
+// score returns the difference between the longest and the shortest chunk in nanoseconds
+func (s *state) score() int64 {
+	return int64(slices.Max(s.totals) - slices.Min(s.totals))
 }
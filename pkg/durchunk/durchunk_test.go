@@ -1,7 +1,10 @@
 package durchunk
 
 import (
+	"encoding/json"
+	"fmt"
 	"maps"
+	"slices"
 	"testing"
 	"time"
 
@@ -92,6 +95,8 @@ func TestSplitBalanced_OneChunk(t *testing.T) {
 	chunks := SplitBalanced(maps.All(data), 1)
 	assert.Len(t, chunks, 1, "expected 1 chunk")
 	assert.ElementsMatch(t, []string{"a", "b"}, chunks[0].Keys, "unexpected keys")
+
+	assert.Nil(t, SplitBalanced(maps.All(data), 0), "no chunk to split into")
 }
 
 func TestSplitBalanced_ChunkCountExceedsKeys(t *testing.T) {
@@ -107,3 +112,135 @@ func TestSplitBalanced_ChunkCountExceedsKeys(t *testing.T) {
 	}
 	assert.Equal(t, 2, keyCount, "total key count mismatch")
 }
+
+func TestSplitBalanced_WithCost(t *testing.T) {
+	data := map[string]time.Duration{
+		"a1": 1 * time.Second,
+		"a2": 1 * time.Second,
+		"a3": 1 * time.Second,
+		"a4": 1 * time.Second,
+		"b1": 14 * time.Second,
+	}
+	// keys of group "a" pay an overhead of 10s once per chunk
+	cost := func(keys []string) time.Duration {
+		var total time.Duration
+		hasA := false
+		for _, k := range keys {
+			total += data[k]
+			hasA = hasA || k[0] == 'a'
+		}
+		if hasA {
+			total += 10 * time.Second
+		}
+		return total
+	}
+	chunks := SplitBalanced(maps.All(data), 2, WithCost(cost))
+	assert.Len(t, chunks, 2)
+
+	for _, c := range chunks {
+		assert.Equal(t, cost(c.Keys), c.Total, "total should be computed with the cost function")
+		if slices.Contains(c.Keys, "a1") {
+			assert.ElementsMatch(t, []string{"a1", "a2", "a3", "a4"}, c.Keys, "group a should not be split")
+		}
+	}
+}
+
+func TestSplitBalanced_WithGroupCost(t *testing.T) {
+	data := map[string]time.Duration{
+		"a1": 1 * time.Second,
+		"a2": 1 * time.Second,
+		"a3": 1 * time.Second,
+		"a4": 1 * time.Second,
+		"b1": 14 * time.Second,
+		"ab": 0, // belongs to both groups
+	}
+	groups := func(key string) []string {
+		if key == "ab" {
+			return []string{"a", "b"}
+		}
+		return []string{key[:1]}
+	}
+	// every group pays an overhead of 10s once per chunk
+	cost := func(group string, keys []string) time.Duration {
+		total := 10 * time.Second
+		for _, k := range keys {
+			total += data[k]
+		}
+		return total
+	}
+	chunks := SplitBalanced(maps.All(data), 2, WithGroupCost(groups, cost), WithSeed(1))
+	assert.Len(t, chunks, 2)
+	// ab pays the overhead of b along with the group a, rather than of a along with b1
+	assert.Equal(t, 24*time.Second, chunks[0].Total)
+	assert.Equal(t, 24*time.Second, chunks[1].Total)
+
+	// the totals updated by the moves match the totals of the whole chunks
+	o := &options{}
+	WithGroupCost(groups, cost)(o)
+	s := newState([]Chunk{{Keys: []string{"a1", "a2", "ab"}}, {Keys: []string{"a3", "a4", "b1"}}}, o)
+	s.move(0, 2, 1, 1)
+	s.swap(0, 0, 1, 3)
+	s.move(1, 0, 0, 0)
+	for i, keys := range s.keys {
+		assert.Equal(t, o.cost(keys), s.totals[i], "chunk %d: %v", i, keys)
+	}
+}
+
+func TestSplitBalanced_SubSecond(t *testing.T) {
+	data := make(map[string]time.Duration)
+	for i := range 100 {
+		data[fmt.Sprintf("t%03d", i)] = 200 * time.Millisecond
+	}
+	chunks := SplitBalanced(maps.All(data), 4)
+	assert.Len(t, chunks, 4)
+
+	var total time.Duration
+	for _, c := range chunks {
+		assert.Len(t, c.Keys, 25, "tests under a second should weigh more than zero")
+		assert.Equal(t, 5*time.Second, c.Total)
+		total += c.Total
+	}
+	assert.Equal(t, 20*time.Second, total)
+}
+
+func TestChunkJSON(t *testing.T) {
+	c := Chunk{Keys: []string{"a"}, Total: 1500 * time.Millisecond}
+	b, err := json.Marshal(c)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"keys":["a"],"total_seconds":1.5}`, string(b))
+
+	var got Chunk
+	assert.NoError(t, json.Unmarshal(b, &got))
+	assert.Equal(t, c, got)
+}
+
+func TestSplitBalanced_WithPinned(t *testing.T) {
+	data := map[string]time.Duration{
+		"a": 5 * time.Second,
+		"b": 5 * time.Second,
+		"c": 1 * time.Second,
+		"d": 1 * time.Second,
+		"e": 1 * time.Second,
+		"f": 1 * time.Second,
+	}
+	// both long keys on the first chunk, an unknown key and an index out of range are ignored
+	chunks := SplitBalanced(maps.All(data), 2, WithPinned(map[string]int{"a": 0, "b": 0, "x": 1, "c": 5}))
+	assert.Len(t, chunks, 2)
+
+	assert.Subset(t, chunks[0].Keys, []string{"a", "b"}, "pinned keys should stay on their chunk")
+	assert.ElementsMatch(t, []string{"c", "d", "e", "f"}, chunks[1].Keys, "the other keys should balance the pinned ones")
+	assert.Equal(t, 10*time.Second, chunks[0].Total)
+	assert.Equal(t, 4*time.Second, chunks[1].Total)
+}
+
+func TestSplitBalanced_WithSeed(t *testing.T) {
+	data := make(map[string]time.Duration)
+	for i := range 30 {
+		data[fmt.Sprintf("k%02d", i)] = time.Duration(i%7+1) * time.Second
+	}
+	// the map is iterated in a different order by each call
+	first := SplitBalanced(maps.All(data), 4, WithSeed(42))
+	for range 5 {
+		assert.Equal(t, first, SplitBalanced(maps.All(data), 4, WithSeed(42)))
+	}
+}